@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultBackend is used when backend is empty in config.
+const DefaultBackend = "kafkago"
+
+type Logger interface {
+	Infof(string, ...interface{})
+	Errorf(string, ...interface{})
+}
+
+// Options are the backend-neutral options of NewConsumer and NewProducer,
+// every backend translates them into its own options.
+type Options struct {
+	Logger Logger
+	// Metrics is not supported by the memory backend
+	Metrics Metrics
+	// TokenProvider overrides the oauthbearer token provider of config,
+	// it is not supported by the memory backend
+	TokenProvider TokenProvider
+
+	// consumer only
+	OnAssigned func([]TopicPartition)
+	OnRevoked  func([]TopicPartition)
+
+	// producer only
+	DeliveryReport func(DeliveryReport)
+}
+
+type Option func(*Options)
+
+func WithLogger(log Logger) Option {
+	return func(o *Options) {
+		o.Logger = log
+	}
+}
+
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+func WithTokenProvider(p TokenProvider) Option {
+	return func(o *Options) {
+		o.TokenProvider = p
+	}
+}
+
+// WithOnAssigned sets the callback of partitions assigned to the consumer
+// by a group rebalance, it is called before any message of them.
+func WithOnAssigned(fn func([]TopicPartition)) Option {
+	return func(o *Options) {
+		o.OnAssigned = fn
+	}
+}
+
+// WithOnRevoked sets the callback of partitions revoked from the consumer
+// by a group rebalance or stop, it is called after their last message
+// is dispatched and before the final offset commit.
+func WithOnRevoked(fn func([]TopicPartition)) Option {
+	return func(o *Options) {
+		o.OnRevoked = fn
+	}
+}
+
+func WithDeliveryReport(fn func(DeliveryReport)) Option {
+	return func(o *Options) {
+		o.DeliveryReport = fn
+	}
+}
+
+type (
+	ConsumerFactory func(c ConsumerConfig, o Options) (Consumer, error)
+	ProducerFactory func(c ProducerConfig, o Options) (Producer, error)
+)
+
+var (
+	backendsMu sync.RWMutex
+	consumers  = make(map[string]ConsumerFactory)
+	producers  = make(map[string]ProducerFactory)
+)
+
+// RegisterConsumer makes a consumer backend available by name,
+// it panics if the name is registered twice.
+func RegisterConsumer(name string, f ConsumerFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if f == nil {
+		panic("kafka: register consumer factory is nil")
+	}
+	if _, dup := consumers[name]; dup {
+		panic("kafka: register consumer twice for backend " + name)
+	}
+	consumers[name] = f
+}
+
+// RegisterProducer makes a producer backend available by name,
+// it panics if the name is registered twice.
+func RegisterProducer(name string, f ProducerFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if f == nil {
+		panic("kafka: register producer factory is nil")
+	}
+	if _, dup := producers[name]; dup {
+		panic("kafka: register producer twice for backend " + name)
+	}
+	producers[name] = f
+}
+
+// Backends returns the sorted names of registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	set := make(map[string]struct{}, len(consumers)+len(producers))
+	for name := range consumers {
+		set[name] = struct{}{}
+	}
+	for name := range producers {
+		set[name] = struct{}{}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewConsumer creates a consumer of the backend selected by config.
+func NewConsumer(c ConsumerConfig, options ...Option) (Consumer, error) {
+	name := backendName(c.Backend)
+	backendsMu.RLock()
+	f, ok := consumers[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown consumer backend %q (forgotten import?)", name)
+	}
+	return f(c, newOptions(options))
+}
+
+// NewProducer creates a producer of the backend selected by config.
+func NewProducer(c ProducerConfig, options ...Option) (Producer, error) {
+	name := backendName(c.Backend)
+	backendsMu.RLock()
+	f, ok := producers[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown producer backend %q (forgotten import?)", name)
+	}
+	return f(c, newOptions(options))
+}
+
+// NewTxnProducer creates a transactional producer by the backend of c,
+// transactional_id must be set.
+func NewTxnProducer(c ProducerConfig, options ...Option) (TxnProducer, error) {
+	if c.TransactionalID == "" {
+		return nil, errors.New("transactional_id is empty")
+	}
+	p, err := NewProducer(c, options...)
+	if err != nil {
+		return nil, err
+	}
+	tp, ok := p.(TxnProducer)
+	if !ok {
+		p.Stop()
+		return nil, fmt.Errorf("producer backend %q does not support transactions", backendName(c.Backend))
+	}
+	return tp, nil
+}
+
+func backendName(name string) string {
+	if name == "" {
+		return DefaultBackend
+	}
+	return name
+}
+
+func newOptions(options []Option) Options {
+	var o Options
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
@@ -0,0 +1,28 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+)
+
+type staticToken string
+
+func (t staticToken) Token(_ context.Context) (Token, error) {
+	return Token{Value: string(t)}, nil
+}
+
+func TestOptionsReachFactory(t *testing.T) {
+	var got Options
+	RegisterConsumer("options_test", func(c ConsumerConfig, o Options) (Consumer, error) {
+		got = o
+		return nil, nil
+	})
+
+	provider := staticToken("token")
+	if _, err := NewConsumer(ConsumerConfig{Backend: "options_test"}, WithTokenProvider(provider)); err != nil {
+		t.Fatal(err)
+	}
+	if got.TokenProvider != provider {
+		t.Fatalf("got token provider %v, want %v", got.TokenProvider, provider)
+	}
+}
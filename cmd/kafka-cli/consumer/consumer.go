@@ -3,9 +3,12 @@ package consumer
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/sko00o/kafka/consumer/kafkago"
+	_ "github.com/sko00o/kafka/consumer/kafkago"
 	"github.com/sko00o/kafka/consumer/sarama"
 
 	log "github.com/sirupsen/logrus"
@@ -15,8 +18,10 @@ import (
 )
 
 var (
-	useSarama bool
-	verbose   bool
+	useSarama  bool
+	verbose    bool
+	fromOffset string
+	fromTime   string
 )
 
 func NewCommand() *cobra.Command {
@@ -25,6 +30,7 @@ func NewCommand() *cobra.Command {
 		PreRunE: helper.BindFlagConfigs(map[string][]string{
 			"group":        {"group_id"},
 			"start-offset": {"start_offset"},
+			"manual-ack":   {"manual_ack"},
 		}),
 		Run: helper.RunFunc(log.New(), func(ctx context.Context, c helper.ConfigUnmarshaler) error {
 			var cfg sk.ConsumerConfig
@@ -33,22 +39,26 @@ func NewCommand() *cobra.Command {
 			}
 			log.Debugf("config: %+v", cfg)
 
-			var wg sync.WaitGroup
-			var consumer sk.Consumer
-			{
-				sLog := &SilentLogger{log.New()}
-				var err error
-				if useSarama {
-					consumer, err = sarama.New(cfg, sarama.WithLogger(sLog))
-					if err != nil {
-						return err
-					}
-				} else {
-					consumer, err = kafkago.New(cfg, kafkago.WithLogger(sLog))
-					if err != nil {
-						return err
-					}
+			if useSarama {
+				cfg.Backend = sarama.Backend
+			}
+			if fromOffset != "" {
+				if err := setFromOffset(&cfg, fromOffset); err != nil {
+					return err
+				}
+			}
+			if fromTime != "" {
+				t, err := parseFromTime(fromTime, time.Now())
+				if err != nil {
+					return err
 				}
+				cfg.StartTime = t
+			}
+
+			var wg sync.WaitGroup
+			consumer, err := sk.NewConsumer(cfg, sk.WithLogger(&SilentLogger{log.New()}))
+			if err != nil {
+				return err
 			}
 			defer func() {
 				log.Info("stop consume...")
@@ -61,15 +71,18 @@ func NewCommand() *cobra.Command {
 				defer wg.Done()
 				for msg := range consumer.Receive() {
 					if verbose {
-						fmt.Printf("Topic: %s Partition: %d Offset: %d\n%s\n",
+						fmt.Printf("Topic: %s Partition: %d Offset: %d Key: %s Time: %s\n%s\n",
 							msg.Topic(),
 							msg.Partition(),
 							msg.Offset(),
+							msg.Key(),
+							msg.Timestamp().Format(time.RFC3339Nano),
 							msg.Value(),
 						)
 					} else {
 						fmt.Printf("%s\n", msg.Value())
 					}
+					msg.Ack()
 				}
 			}()
 
@@ -85,16 +98,68 @@ func NewCommand() *cobra.Command {
 
 	flags := cmd.Flags()
 	flags.StringSliceP("topics", "t", []string{"test_topic"}, "topics for consume")
-	flags.StringP("group", "g", "test_group", "topics for consume")
+	flags.StringP("group", "g", "test_group", "consumer group, empty to read partitions without group")
+	flags.IntSlice("partitions", nil, "partitions to read without group (default all)")
 	flags.StringP("start-offset", "s", "last", "set start offset")
 	flags.StringP("version", "v", "", "set kafka version (optional)")
+	flags.Bool("manual-ack", false, "commit offsets after messages are printed")
+	flags.StringVar(&fromOffset, "from-offset", "", "replay from an offset of every partition, or from partition:offset,... of every topic")
+	flags.StringVar(&fromTime, "from-time", "", "replay from a RFC3339 time, or a duration ago like 1h")
 
+	flags.String("backend", sk.DefaultBackend, "client backend: "+strings.Join(sk.Backends(), ", "))
 	flags.BoolVar(&useSarama, "sarama", false, "use sarama client")
+	_ = flags.MarkDeprecated("sarama", "use --backend sarama instead")
 	flags.BoolVar(&verbose, "verbose", false, "print verbose")
 
 	return cmd
 }
 
+// setFromOffset parses "offset" or "partition:offset,...".
+func setFromOffset(cfg *sk.ConsumerConfig, v string) error {
+	if !strings.Contains(v, ":") {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("parse from-offset %s: %w", v, err)
+		}
+		cfg.StartOffset = v
+		return nil
+	}
+
+	for _, item := range strings.Split(v, ",") {
+		p, o, ok := strings.Cut(item, ":")
+		if !ok {
+			return fmt.Errorf("parse from-offset %s: want partition:offset", item)
+		}
+		partition, err := strconv.ParseInt(p, 10, 32)
+		if err != nil {
+			return fmt.Errorf("parse from-offset %s: %w", item, err)
+		}
+		offset, err := strconv.ParseInt(o, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse from-offset %s: %w", item, err)
+		}
+		for _, topic := range cfg.Topics {
+			cfg.StartOffsets = append(cfg.StartOffsets, sk.PartitionOffset{
+				Topic:     topic,
+				Partition: int32(partition),
+				Offset:    offset,
+			})
+		}
+	}
+	return nil
+}
+
+// parseFromTime parses a RFC3339 time or a duration before now.
+func parseFromTime(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse from-time %s: %w", v, err)
+	}
+	return t, nil
+}
+
 type SilentLogger struct {
 	*log.Logger
 }
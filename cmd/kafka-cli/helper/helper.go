@@ -7,7 +7,9 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -119,10 +121,26 @@ type Logger interface {
 
 func RunFunc(log Logger, f func(ctx context.Context, cfg ConfigUnmarshaler) error) CobraRun {
 	return elegantQuit(log, func(ctx context.Context, _ *cobra.Command, _ []string) error {
-		return f(ctx, allConfig)
+		return f(ctx, configUnmarshaler{allConfig})
 	})
 }
 
+// configUnmarshaler decodes RFC3339 strings into time.Time as well,
+// e.g. start_time of consumers.
+type configUnmarshaler struct {
+	*viper.Viper
+}
+
+func (c configUnmarshaler) Unmarshal(v interface{}, opts ...viper.DecoderConfigOption) error {
+	// NOTE: the hook replaces the default ones of viper
+	hook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+	))
+	return c.Viper.Unmarshal(v, append([]viper.DecoderConfigOption{hook}, opts...)...)
+}
+
 func elegantQuit(log Logger, fn ctxRun) CobraRun {
 	if log == nil {
 		log = &NoLog{}
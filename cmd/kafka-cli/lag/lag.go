@@ -0,0 +1,126 @@
+package lag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/sko00o/kafka/consumer/kafkago"
+	_ "github.com/sko00o/kafka/consumer/sarama"
+
+	log "github.com/sirupsen/logrus"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/cmd/kafka-cli/helper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	output string
+	watch  time.Duration
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "lag",
+		PreRunE: helper.BindFlagConfigs(map[string][]string{
+			"group": {"group_id"},
+		}),
+		Run: helper.RunFunc(log.New(), func(ctx context.Context, c helper.ConfigUnmarshaler) error {
+			var cfg sk.ConsumerConfig
+			if err := c.Unmarshal(&cfg); err != nil {
+				return err
+			}
+			log.Debugf("config: %+v", cfg)
+
+			if cfg.GroupID == "" {
+				return errors.New("group is empty")
+			}
+			printLags, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			r, err := sk.NewLagReader(cfg)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			for {
+				lags, err := r.Lag(ctx, cfg.GroupID, cfg.Topics...)
+				if err != nil {
+					return err
+				}
+				if err := printLags(os.Stdout, lags); err != nil {
+					return err
+				}
+				if watch <= 0 {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(watch):
+				}
+			}
+		}),
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceP("topics", "t", nil, "topics of the group (default topics committed by the group)")
+	flags.StringP("group", "g", "", "consumer group")
+	flags.StringP("version", "v", "", "set kafka version (optional)")
+	flags.StringVarP(&output, "output", "o", "table", "output format: table or json")
+	flags.DurationVarP(&watch, "watch", "w", 0, "refresh interval, e.g. 5s (default print once)")
+
+	flags.String("backend", sk.DefaultBackend, "client backend: "+strings.Join(sk.Backends(), ", "))
+
+	return cmd
+}
+
+type printer func(w io.Writer, lags []sk.PartitionLag) error
+
+func newPrinter(format string) (printer, error) {
+	switch format {
+	case "table":
+		return printTable, nil
+	case "json":
+		return printJSON, nil
+	default:
+		return nil, fmt.Errorf("output %s not support", format)
+	}
+}
+
+func printTable(w io.Writer, lags []sk.PartitionLag) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tCOMMITTED\tEND\tLAG")
+	var total int64
+	for _, l := range lags {
+		committed, lag := "-", "-"
+		if l.Committed >= 0 {
+			committed = fmt.Sprint(l.Committed)
+		}
+		if l.Lag >= 0 {
+			lag = fmt.Sprint(l.Lag)
+			total += l.Lag
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\n", l.Topic, l.Partition, committed, l.End, lag)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t\t%d\n", total)
+	return tw.Flush()
+}
+
+// printJSON prints lags as a JSON line, so a watch is a stream of lines.
+func printJSON(w io.Writer, lags []sk.PartitionLag) error {
+	if lags == nil {
+		lags = []sk.PartitionLag{}
+	}
+	return json.NewEncoder(w).Encode(lags)
+}
@@ -4,6 +4,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/sko00o/kafka/cmd/kafka-cli/consumer"
 	"github.com/sko00o/kafka/cmd/kafka-cli/helper"
+	"github.com/sko00o/kafka/cmd/kafka-cli/lag"
 	"github.com/sko00o/kafka/cmd/kafka-cli/producer"
 	"github.com/spf13/cobra"
 )
@@ -11,17 +12,50 @@ import (
 var rootCmd = &cobra.Command{
 	Use: "kafka-cli",
 	PersistentPreRunE: helper.PersistentBindFlagConfigs(map[string][]string{
-		"brokers": {"addresses"},
+		"brokers":            {"addresses"},
+		"tls-enable":         {"tls", "enable"},
+		"tls-ca":             {"tls", "ca_file"},
+		"tls-cert":           {"tls", "cert_file"},
+		"tls-key":            {"tls", "key_file"},
+		"tls-server-name":    {"tls", "server_name"},
+		"tls-insecure":       {"tls", "insecure_skip_verify"},
+		"tls-min-version":    {"tls", "min_version"},
+		"sasl-mechanism":     {"sasl", "mechanism"},
+		"sasl-username":      {"sasl", "username"},
+		"sasl-password":      {"sasl", "password"},
+		"sasl-token":         {"sasl", "token"},
+		"sasl-token-file":    {"sasl", "token_file"},
+		"sasl-token-url":     {"sasl", "token_url"},
+		"sasl-client-id":     {"sasl", "client_id"},
+		"sasl-client-secret": {"sasl", "client_secret"},
+		"sasl-scopes":        {"sasl", "scopes"},
 	}),
 }
 
 func init() {
 	flags := rootCmd.PersistentFlags()
 	flags.StringSliceP("brokers", "k", []string{"127.0.0.1:9092"}, "kafka brokers")
+	flags.Bool("tls-enable", false, "enable tls")
+	flags.String("tls-ca", "", "tls ca file")
+	flags.String("tls-cert", "", "tls client cert file")
+	flags.String("tls-key", "", "tls client key file")
+	flags.String("tls-server-name", "", "tls server name")
+	flags.Bool("tls-insecure", false, "skip tls server verification")
+	flags.String("tls-min-version", "", "tls min version, e.g. 1.2")
+	flags.String("sasl-mechanism", "", "sasl mechanism: plain, scram_sha_256, scram_sha_512 or oauthbearer")
+	flags.String("sasl-username", "", "sasl username")
+	flags.String("sasl-password", "", "sasl password")
+	flags.String("sasl-token", "", "oauthbearer static token")
+	flags.String("sasl-token-file", "", "oauthbearer token file, read again on expiry")
+	flags.String("sasl-token-url", "", "oauthbearer client credentials token endpoint")
+	flags.String("sasl-client-id", "", "oauthbearer client id")
+	flags.String("sasl-client-secret", "", "oauthbearer client secret")
+	flags.StringSlice("sasl-scopes", nil, "oauthbearer scopes")
 
 	rootCmd.AddCommand(
 		consumer.NewCommand(),
 		producer.NewCommand(),
+		lag.NewCommand(),
 	)
 }
 
@@ -6,13 +6,14 @@ import (
 	"encoding/hex"
 	"math/rand"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	sk "github.com/sko00o/kafka"
 	"github.com/sko00o/kafka/cmd/kafka-cli/helper"
-	"github.com/sko00o/kafka/producer/kafkago"
+	_ "github.com/sko00o/kafka/producer/kafkago"
 	"github.com/sko00o/kafka/producer/sarama"
 	"github.com/spf13/cobra"
 )
@@ -43,9 +44,13 @@ func NewCommand() *cobra.Command {
 	flags.StringP("compression", "p", "", "compression for produce")
 	flags.BoolP("async", "a", false, "enable async mode")
 	flags.StringP("version", "v", "", "set kafka version (optional)")
+	flags.Bool("idempotent", false, "enable idempotent producer (sarama only)")
+	flags.String("balancer", "", "balancer: "+strings.Join(sk.Partitioners(), ", "))
 
 	flags.StringVarP(&topic, "topic", "t", "test_topic", "topic for produce")
+	flags.String("backend", sk.DefaultBackend, "client backend: "+strings.Join(sk.Backends(), ", "))
 	flags.BoolVar(&useSarama, "sarama", false, "use sarama client")
+	_ = flags.MarkDeprecated("sarama", "use --backend sarama instead")
 	flags.BoolVar(&typeMode, "type", false, "type mode")
 	flags.DurationVar(&sendInterval, "interval", 2*time.Second, "set send interval in auto mode")
 
@@ -53,21 +58,13 @@ func NewCommand() *cobra.Command {
 }
 
 func runProducer(ctx context.Context, cfg sk.ProducerConfig, typeMode bool, sendInterval time.Duration) error {
-	var producer sk.Producer
-	{
-		sLog := log.New()
-		var err error
-		if useSarama {
-			producer, err = sarama.New(cfg, sarama.WithLogger(sLog))
-			if err != nil {
-				return err
-			}
-		} else {
-			producer, err = kafkago.New(cfg, kafkago.WithLogger(sLog))
-			if err != nil {
-				return err
-			}
-		}
+	if useSarama {
+		cfg.Backend = sarama.Backend
+	}
+
+	producer, err := sk.NewProducer(cfg, sk.WithLogger(log.New()))
+	if err != nil {
+		return err
 	}
 	defer producer.Stop()
 
@@ -91,7 +88,7 @@ func runProducer(ctx context.Context, cfg sk.ProducerConfig, typeMode bool, send
 				msg := input.Bytes()
 
 				start := time.Now()
-				if err := producer.Send(topic, msg); err != nil {
+				if err := producer.SendContext(innerCtx, topic, msg); err != nil {
 					if err == innerCtx.Err() {
 						return
 					}
@@ -116,7 +113,7 @@ func runProducer(ctx context.Context, cfg sk.ProducerConfig, typeMode bool, send
 				rand.Read(msg[:])
 				start := time.Now()
 				msg := hex.EncodeToString(msg[:])
-				if err := producer.Send(topic, []byte(msg)); err != nil {
+				if err := producer.SendContext(innerCtx, topic, []byte(msg)); err != nil {
 					if err == innerCtx.Err() {
 						return
 					}
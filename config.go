@@ -1,15 +1,21 @@
 package kafka
 
 import (
+	"context"
 	"time"
 )
 
 type ConsumerConfig struct {
+	Backend     string   `mapstructure:"backend"`
 	WorkerCnt   uint32   `mapstructure:"worker_cnt"`
 	Addresses   []string `mapstructure:"addresses"`
 	Topics      []string `mapstructure:"topics"`
 	GroupID     string   `mapstructure:"group_id"`
-	StartOffset string   `mapstructure:"start_offset"`
+	StartOffset string   `mapstructure:"start_offset"` // first, last or an offset of every partition
+
+	// group-less mode only, partitions of every topic to read,
+	// empty means all of them
+	Partitions []int32 `mapstructure:"partitions"`
 
 	// sarama only
 	Version      string `mapstructure:"version"`
@@ -17,13 +23,37 @@ type ConsumerConfig struct {
 
 	MinBytes         int           `mapstructure:"min_bytes"`
 	MaxBytes         int           `mapstructure:"max_bytes"`
+	IsolationLevel   string        `mapstructure:"isolation_level"` // read_uncommitted (default) or read_committed
 	CommitSync       bool          `mapstructure:"commit_sync"`
+	ManualAck        bool          `mapstructure:"manual_ack"`
 	CommitInterval   time.Duration `mapstructure:"commit_interval"`
 	SessionTimeout   time.Duration `mapstructure:"session_timeout"`
 	RebalanceTimeout time.Duration `mapstructure:"rebalance_timeout"`
+
+	// replay from explicit positions, they override committed offsets
+	// the first time a partition is assigned
+	StartOffsets []PartitionOffset `mapstructure:"start_offsets"`
+	// an RFC3339 time in config files of kafka-cli
+	StartTime time.Time `mapstructure:"start_time"`
+
+	// enables batch mode
+	Batch *BatchConfig `mapstructure:"batch"`
+
+	// Serve only
+	Ordering     string        `mapstructure:"ordering"`
+	ErrorPolicy  string        `mapstructure:"error_policy"`
+	MaxRetries   int           `mapstructure:"max_retries"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+
+	// retry and dead letter topics, see package retry
+	Retry *RetryConfig `mapstructure:"retry"`
+
+	TLS  *TLSConfig  `mapstructure:"tls"`
+	SASL *SASLConfig `mapstructure:"sasl"`
 }
 
 type ProducerConfig struct {
+	Backend     string   `mapstructure:"backend"`
 	Addresses   []string `mapstructure:"addresses"`
 	Async       bool     `mapstructure:"async"`
 	Compression string   `mapstructure:"compression"`
@@ -34,10 +64,20 @@ type ProducerConfig struct {
 	BufferSize        int           `mapstructure:"buffer_size"`
 	DialTimeout       time.Duration `mapstructure:"dial_timeout"`
 
+	// not supported by kafka-go, transactional_id implies idempotent,
+	// idempotent implies required_acks -1 and version >= 0.11.0.0,
+	// required_acks 0 is taken as unset and silently upgraded to -1
+	Idempotent      bool   `mapstructure:"idempotent"`
+	TransactionalID string `mapstructure:"transactional_id"`
+
 	// kafka-go only
-	Balancer           string `mapstructure:"balancer"`
-	BalancerConsistent bool   `mapstructure:"balancer_consistent"`
-	BatchQueueSize     int    `mapstructure:"batch_queue_size"`
+	BatchQueueSize int `mapstructure:"batch_queue_size"`
+
+	// roundrobin, hash, murmur2, crc32, manual, sticky or a registered one,
+	// empty to use the default of backend, leastbytes is kafka-go only
+	Balancer string `mapstructure:"balancer"`
+	// hash messages without key as an empty key
+	BalancerConsistent bool `mapstructure:"balancer_consistent"`
 
 	MaxAttempts  int           `mapstructure:"max_attempts"`
 	RequiredAcks int           `mapstructure:"required_acks"`
@@ -47,9 +87,69 @@ type ProducerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 
-	SASL *struct {
-		Mechanism string `mapstructure:"mechanism"`
-		Username  string `mapstructure:"username"`
-		Password  string `mapstructure:"password"`
-	} `mapstructure:"sasl"`
+	TLS  *TLSConfig  `mapstructure:"tls"`
+	SASL *SASLConfig `mapstructure:"sasl"`
+}
+
+type BatchConfig struct {
+	MaxMessages int           `mapstructure:"max_messages"`
+	MaxBytes    int           `mapstructure:"max_bytes"`
+	MaxWait     time.Duration `mapstructure:"max_wait"`
+}
+
+// PartitionOffset is an offset of a partition, FirstOffset and LastOffset
+// are accepted as well.
+type PartitionOffset struct {
+	Topic     string `mapstructure:"topic"`
+	Partition int32  `mapstructure:"partition"`
+	Offset    int64  `mapstructure:"offset"`
+}
+
+type RetryConfig struct {
+	// failed messages go through stages in order,
+	// then to the dead letter topic
+	Stages          []RetryStage `mapstructure:"stages"`
+	DeadLetterTopic string       `mapstructure:"dead_letter_topic"`
+}
+
+type RetryStage struct {
+	Topic string        `mapstructure:"topic"`
+	Delay time.Duration `mapstructure:"delay"`
+}
+
+type TLSConfig struct {
+	Enable             bool   `mapstructure:"enable"`
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	MinVersion         string `mapstructure:"min_version"`
+}
+
+type SASLConfig struct {
+	Mechanism string `mapstructure:"mechanism"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+
+	// oauthbearer only
+	Token         string        `mapstructure:"token"`
+	TokenFile     string        `mapstructure:"token_file"`
+	TokenLifetime time.Duration `mapstructure:"token_lifetime"`
+	TokenURL      string        `mapstructure:"token_url"`
+	ClientID      string        `mapstructure:"client_id"`
+	ClientSecret  string        `mapstructure:"client_secret"`
+	Scopes        []string      `mapstructure:"scopes"`
+}
+
+// Token is an OAuth bearer token, a zero Expiry means it never expires.
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// TokenProvider provides tokens for the oauthbearer mechanism,
+// implementations should cache tokens until they expire.
+type TokenProvider interface {
+	Token(ctx context.Context) (Token, error)
 }
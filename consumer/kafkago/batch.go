@@ -0,0 +1,90 @@
+package kafkago
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/ack"
+	"github.com/sko00o/kafka/internal/batch"
+)
+
+// consumeBatch collects messages of a single partition reader into batches.
+func (h *Handler) consumeBatch(ctx context.Context, c *committer, window *ack.Window, tp sk.TopicPartition, reader *kafka.Reader) {
+	var (
+		b        *batch.Batch
+		last     kafka.Message
+		deadline time.Time
+	)
+	for {
+		if h.paused.IsPaused(tp) {
+			// NOTE: deliver the pending batch before waiting
+			if b != nil {
+				if !h.flushBatch(ctx, c, b, last) {
+					return
+				}
+				b = nil
+			}
+			if err := h.paused.Wait(ctx, tp); err != nil {
+				return
+			}
+		}
+
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if b != nil {
+			// NOTE: stop waiting at the deadline of the pending batch
+			fetchCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				// generation ended or reader closed
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				if !h.flushBatch(ctx, c, b, last) {
+					return
+				}
+				b = nil
+				continue
+			}
+
+			if h.log != nil {
+				h.log.Errorf("fetch message: %v", err)
+			}
+			continue
+		}
+
+		if b == nil {
+			b = batch.New(msg.Topic, int32(msg.Partition))
+			deadline = time.Now().Add(h.limits.MaxWait)
+		}
+		b.Add(h.message(c, window, msg))
+		last = msg
+		if b.Full(h.limits) {
+			if !h.flushBatch(ctx, c, b, last) {
+				return
+			}
+			b = nil
+		}
+	}
+}
+
+// flushBatch delivers b and commits it unless manual ack is enabled,
+// it returns false if the generation ended.
+func (h *Handler) flushBatch(ctx context.Context, c *committer, b *batch.Batch, last kafka.Message) bool {
+	select {
+	case h.batchChan <- b:
+	case <-ctx.Done():
+		return false
+	}
+
+	if !h.manualAck {
+		h.commit(c, last.Topic, last.Partition, last.Offset+1)
+	}
+	return true
+}
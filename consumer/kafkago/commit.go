@@ -0,0 +1,63 @@
+package kafkago
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+)
+
+// committer commits offsets to a single generation, either on every call
+// or stashed until flush.
+type committer struct {
+	gen  *kafka.Generation
+	sync bool
+
+	mu      sync.Mutex
+	offsets map[string]map[int]int64
+}
+
+func (c *committer) commit(topic string, partition int, next int64) error {
+	if c.sync {
+		return c.gen.CommitOffsets(map[string]map[int]int64{
+			topic: {partition: next},
+		})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.offsets == nil {
+		c.offsets = make(map[string]map[int]int64)
+	}
+	if c.offsets[topic] == nil {
+		c.offsets[topic] = make(map[int]int64)
+	}
+	c.offsets[topic][partition] = next
+	return nil
+}
+
+func (c *committer) flush() error {
+	c.mu.Lock()
+	offsets := c.offsets
+	c.offsets = nil
+	c.mu.Unlock()
+
+	return c.gen.CommitOffsets(offsets)
+}
+
+func topicPartitions(assignments map[string][]kafka.PartitionAssignment) []sk.TopicPartition {
+	var tps []sk.TopicPartition
+	for topic, partitions := range assignments {
+		for _, p := range partitions {
+			tps = append(tps, sk.TopicPartition{Topic: topic, Partition: int32(p.ID)})
+		}
+	}
+	sort.Slice(tps, func(i, j int) bool {
+		if tps[i].Topic != tps[j].Topic {
+			return tps[i].Topic < tps[j].Topic
+		}
+		return tps[i].Partition < tps[j].Partition
+	})
+	return tps
+}
@@ -3,33 +3,74 @@ package kafkago
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/ack"
+	"github.com/sko00o/kafka/internal/batch"
+	"github.com/sko00o/kafka/internal/pause"
+	"github.com/sko00o/kafka/internal/seek"
+	"github.com/sko00o/kafka/sasl"
 )
 
+const partitionRetryBackoff = time.Second
+
 type Handler struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
-	reader  *kafka.Reader
+	group   *kafka.ConsumerGroup
 	msgChan chan sk.Message
 	log     Logger
+	wg      sync.WaitGroup
+
+	// group-less mode if ID is empty
+	groupConfig kafka.ConsumerGroupConfig
+	partitions  []int32
+	// template of partition readers
+	readerConfig   kafka.ReaderConfig
+	commitInterval time.Duration
+
+	resets  *seek.Resets
+	paused  pause.Set
+	mu      sync.Mutex
+	readers map[sk.TopicPartition]*kafka.Reader
+
+	metrics sk.Metrics
+	// stats of readers closed since the last report
+	closed []kafka.ReaderStats
+
+	tokenProvider sasl.TokenProvider
+
+	onAssigned func([]sk.TopicPartition)
+	onRevoked  func([]sk.TopicPartition)
+
+	manualAck bool
+
+	batchChan chan sk.Batch
+	limits    batch.Limits
 }
 
 func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	h := &Handler{
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:       ctx,
+		cancel:    cancel,
+		manualAck: c.ManualAck,
+		readers:   make(map[sk.TopicPartition]*kafka.Reader),
 	}
 	if cnt := int(c.WorkerCnt); cnt > 0 {
 		h.msgChan = make(chan sk.Message, cnt)
 	} else {
 		h.msgChan = make(chan sk.Message, 1)
 	}
+	if c.Batch != nil {
+		h.batchChan = make(chan sk.Batch, cap(h.msgChan))
+		h.limits = batch.NewLimits(c.Batch)
+	}
 
 	// NOTE: we need to set logger in kafka reader, so we call OptionFunc here
 	for _, option := range options {
@@ -38,82 +79,401 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 		}
 	}
 
-	cfg := kafka.ReaderConfig{
-		Brokers:     c.Addresses,
-		GroupTopics: c.Topics,
+	gc := kafka.ConsumerGroupConfig{
+		Brokers: c.Addresses,
+		Topics:  c.Topics,
+	}
+	rc := kafka.ReaderConfig{
+		Brokers: c.Addresses,
 	}
 	if h.log != nil {
-		cfg.Logger = kafka.LoggerFunc(h.log.Infof)
-		cfg.ErrorLogger = kafka.LoggerFunc(h.log.Errorf)
+		gc.Logger = kafka.LoggerFunc(h.log.Infof)
+		gc.ErrorLogger = kafka.LoggerFunc(h.log.Errorf)
+		rc.Logger = gc.Logger
+		rc.ErrorLogger = gc.ErrorLogger
 	}
 	if v := c.MinBytes; v > 0 {
-		cfg.MinBytes = v
+		rc.MinBytes = v
 	}
 	if v := c.MaxBytes; v > 0 {
-		cfg.MaxBytes = v
+		rc.MaxBytes = v
 	}
-	if v := c.GroupID; v != "" {
-		cfg.GroupID = v
-	} else {
-		return nil, errors.New("group_id is empty")
+	switch v := c.IsolationLevel; v {
+	case "", "read_uncommitted":
+	case "read_committed":
+		rc.IsolationLevel = kafka.ReadCommitted
+	default:
+		return nil, fmt.Errorf("isolation_level %s not support", v)
 	}
+	if len(c.Topics) == 0 {
+		return nil, errors.New("topics is empty")
+	}
+	// NOTE: no group_id means group-less mode, offsets are not committed
+	gc.ID = c.GroupID
+	h.partitions = c.Partitions
 	if !c.CommitSync {
-		cfg.CommitInterval = 2 * time.Second
+		h.commitInterval = 2 * time.Second
 		if v := c.CommitInterval; v != 0 {
-			cfg.CommitInterval = v
+			h.commitInterval = v
 		}
 	}
-	if v := c.StartOffset; v != "" {
-		switch strings.ToLower(v) {
-		case "first":
-			cfg.StartOffset = kafka.FirstOffset
-		case "last":
-			cfg.StartOffset = kafka.LastOffset
-		}
+	resets, err := seek.New(c)
+	if err != nil {
+		return nil, err
+	}
+	h.resets = resets
+	if v := resets.Initial; v != 0 {
+		gc.StartOffset = v
 	}
 	if v := c.SessionTimeout; v != 0 {
-		cfg.SessionTimeout = v
+		gc.SessionTimeout = v
 	}
 	if v := c.RebalanceTimeout; v != 0 {
-		cfg.RebalanceTimeout = v
+		gc.RebalanceTimeout = v
+	}
+
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	mechanism, err := sasl.KafkaGoMechanism(c.SASL, h.tokenProvider)
+	if err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
+	if tlsCfg != nil || mechanism != nil {
+		gc.Dialer = &kafka.Dialer{
+			Timeout:       10 * time.Second,
+			DualStack:     true,
+			TLS:           tlsCfg,
+			SASLMechanism: mechanism,
+		}
+		rc.Dialer = gc.Dialer
 	}
-	h.reader = kafka.NewReader(cfg)
+
+	if gc.ID != "" {
+		if err := gc.Validate(); err != nil {
+			return nil, fmt.Errorf("config validate: %w", err)
+		}
+	}
+	probe := rc
+	probe.Topic = c.Topics[0]
+	if err := probe.Validate(); err != nil {
+		return nil, fmt.Errorf("config validate: %w", err)
+	}
+	h.groupConfig = gc
+	h.readerConfig = rc
 
 	return h, nil
 }
 
 func (h *Handler) Run() error {
+	if h.groupConfig.ID == "" {
+		return h.runStandalone()
+	}
+
+	group, err := kafka.NewConsumerGroup(h.groupConfig)
+	if err != nil {
+		return fmt.Errorf("new consumer group: %w", err)
+	}
+	h.group = group
+	if h.metrics != nil {
+		go h.reportMetrics()
+	}
+
 	go func() {
 		defer close(h.msgChan)
+		if h.batchChan != nil {
+			defer close(h.batchChan)
+		}
+		if h.metrics != nil {
+			defer h.report()
+		}
+		// NOTE: partitions of the last generation may still be dispatching
+		defer h.wg.Wait()
 
 		for {
-			msg, err := h.reader.ReadMessage(h.ctx)
+			gen, err := h.group.Next(h.ctx)
 			if err != nil {
-				if errors.Is(err, io.EOF) ||
+				if errors.Is(err, kafka.ErrGroupClosed) ||
 					errors.Is(err, context.Canceled) {
-					// reader closed
+					// group closed
 					return
 				}
 
 				if h.log != nil {
-					h.log.Errorf("read message: %v", err)
+					h.log.Errorf("next generation: %v", err)
 				}
 				continue
 			}
 
-			h.msgChan <- Message{msg}
+			h.consume(gen)
 		}
 	}()
 
 	return nil
 }
 
+// consume starts a reader for every partition assigned in gen, they
+// return when the generation ends by a rebalance or Stop.
+func (h *Handler) consume(gen *kafka.Generation) {
+	tps := topicPartitions(gen.Assignments)
+	if h.onAssigned != nil {
+		h.onAssigned(tps)
+	}
+
+	c := &committer{gen: gen, sync: h.commitInterval == 0}
+	var partitions sync.WaitGroup
+	for topic, assignments := range gen.Assignments {
+		for _, a := range assignments {
+			topic, a := topic, a
+			partitions.Add(1)
+			h.wg.Add(1)
+			gen.Start(func(ctx context.Context) {
+				defer h.wg.Done()
+				defer partitions.Done()
+				h.consumePartition(ctx, c, topic, a.ID, a.Offset)
+			})
+		}
+	}
+
+	h.wg.Add(1)
+	gen.Start(func(ctx context.Context) {
+		defer h.wg.Done()
+
+		if !c.sync {
+			ticker := time.NewTicker(h.commitInterval)
+		loop:
+			for {
+				select {
+				case <-ticker.C:
+					h.flush(c)
+				case <-ctx.Done():
+					break loop
+				}
+			}
+			ticker.Stop()
+		}
+
+		partitions.Wait()
+		if h.onRevoked != nil {
+			h.onRevoked(tps)
+		}
+		h.flush(c)
+	})
+}
+
+// consumePartition reads a partition until ctx is done, a partition
+// failed to be positioned is retried after partitionRetryBackoff.
+func (h *Handler) consumePartition(ctx context.Context, c *committer, topic string, partition int, offset int64) {
+	for {
+		err := h.readPartition(ctx, c, topic, partition, offset)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if h.log != nil {
+			h.log.Errorf("consume %s/%d: %v, retry in %s", topic, partition, err, partitionRetryBackoff)
+		}
+
+		timer := time.NewTimer(partitionRetryBackoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// readPartition returns an error only if the reader failed to be positioned.
+func (h *Handler) readPartition(ctx context.Context, c *committer, topic string, partition int, offset int64) error {
+	rc := h.readerConfig
+	rc.Topic = topic
+	rc.Partition = partition
+	reader := kafka.NewReader(rc)
+	// NOTE: closing waits for the fetch in flight, which must not delay
+	// the flush of the generation past the session timeout
+	defer func() {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.closeReader(reader)
+		}()
+	}()
+
+	if err := reader.SetOffset(offset); err != nil {
+		return fmt.Errorf("set offset: %w", err)
+	}
+
+	tp := sk.TopicPartition{Topic: topic, Partition: int32(partition)}
+	h.mu.Lock()
+	h.readers[tp] = reader
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.readers, tp)
+		h.mu.Unlock()
+	}()
+	if err := h.reset(ctx, tp); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	// NOTE: the window lives as long as the assignment, acks arriving
+	// after a rebalance only commit to the ended generation.
+	var window *ack.Window
+	if h.manualAck && c != nil {
+		window = new(ack.Window)
+	}
+
+	if h.batchChan != nil {
+		h.consumeBatch(ctx, c, window, tp, reader)
+		return nil
+	}
+
+	for {
+		if err := h.paused.Wait(ctx, tp); err != nil {
+			return nil
+		}
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				// generation ended or reader closed
+				return nil
+			}
+
+			if h.log != nil {
+				h.log.Errorf("fetch message: %v", err)
+			}
+			continue
+		}
+
+		select {
+		case h.msgChan <- h.message(c, window, msg):
+		case <-ctx.Done():
+			return nil
+		}
+		if !h.manualAck {
+			h.commit(c, msg.Topic, msg.Partition, msg.Offset+1)
+		}
+	}
+}
+
+// reset moves the reader of tp to its pending position, if any.
+func (h *Handler) reset(ctx context.Context, tp sk.TopicPartition) error {
+	h.mu.Lock()
+	reader := h.readers[tp]
+	h.mu.Unlock()
+	if reader == nil {
+		return nil
+	}
+
+	p, ok := h.resets.Take(tp)
+	if !ok {
+		return nil
+	}
+	var err error
+	if !p.Time.IsZero() {
+		err = reader.SetOffsetAt(ctx, p.Time)
+	} else {
+		err = reader.SetOffset(p.Offset)
+	}
+	if err != nil {
+		// NOTE: keep it for the next assignment
+		h.resets.Seek(tp, p)
+	}
+	return err
+}
+
+// Seek implements sk.Seeker.
+func (h *Handler) Seek(offsets ...sk.PartitionOffset) error {
+	for _, o := range offsets {
+		if err := seek.Validate(o); err != nil {
+			return err
+		}
+	}
+	for _, o := range offsets {
+		tp := sk.TopicPartition{Topic: o.Topic, Partition: o.Partition}
+		h.resets.Seek(tp, seek.Position{Offset: o.Offset})
+		if err := h.reset(h.ctx, tp); err != nil {
+			return fmt.Errorf("seek %s/%d: %w", tp.Topic, tp.Partition, err)
+		}
+	}
+	return nil
+}
+
+// SeekTime implements sk.Seeker.
+func (h *Handler) SeekTime(t time.Time) error {
+	h.mu.Lock()
+	tps := make([]sk.TopicPartition, 0, len(h.readers))
+	for tp := range h.readers {
+		tps = append(tps, tp)
+	}
+	h.mu.Unlock()
+
+	for _, tp := range tps {
+		h.resets.Seek(tp, seek.Position{Time: t})
+		if err := h.reset(h.ctx, tp); err != nil {
+			return fmt.Errorf("seek %s/%d: %w", tp.Topic, tp.Partition, err)
+		}
+	}
+	return nil
+}
+
+// Pause implements sk.Pauser, the reader of a paused partition stops
+// fetching once its queue is full.
+func (h *Handler) Pause(partitions ...sk.TopicPartition) {
+	h.paused.Pause(partitions...)
+}
+
+func (h *Handler) Resume(partitions ...sk.TopicPartition) {
+	h.paused.Resume(partitions...)
+}
+
+func (h *Handler) Paused() []sk.TopicPartition {
+	return h.paused.List()
+}
+
+func (h *Handler) message(c *committer, window *ack.Window, msg kafka.Message) Message {
+	m := Message{Message: msg}
+	if window == nil {
+		return m
+	}
+
+	m.acker = window.Track(msg.Offset, func(next int64) {
+		h.commit(c, msg.Topic, msg.Partition, next)
+	})
+	return m
+}
+
+func (h *Handler) commit(c *committer, topic string, partition int, next int64) {
+	if c == nil {
+		// group-less mode
+		return
+	}
+	if err := c.commit(topic, partition, next); err != nil {
+		if h.log != nil {
+			h.log.Errorf("commit offsets: %v", err)
+		}
+	}
+}
+
+func (h *Handler) flush(c *committer) {
+	if err := c.flush(); err != nil {
+		if h.log != nil {
+			h.log.Errorf("commit offsets: %v", err)
+		}
+	}
+}
+
 func (h *Handler) Stop() {
 	h.cancel()
-	if err := h.reader.Close(); err != nil {
+	if h.group == nil {
+		// group-less mode or not running
+		return
+	}
+	if err := h.group.Close(); err != nil {
 		// will not get error actually
 		if h.log != nil {
-			h.log.Errorf("stop reader: %v", err)
+			h.log.Errorf("stop group: %v", err)
 		}
 	}
 }
@@ -122,14 +482,38 @@ func (h *Handler) Receive() <-chan sk.Message {
 	return h.msgChan
 }
 
+// ReceiveBatch returns nil if batch mode is disabled.
+func (h *Handler) ReceiveBatch() <-chan sk.Batch {
+	return h.batchChan
+}
+
 type Message struct {
 	kafka.Message
+	acker *ack.Handle
+}
+
+func (m Message) Key() []byte {
+	return m.Message.Key
 }
 
 func (m Message) Value() []byte {
 	return m.Message.Value
 }
 
+func (m Message) Headers() []sk.Header {
+	if len(m.Message.Headers) == 0 {
+		return nil
+	}
+	headers := make([]sk.Header, 0, len(m.Message.Headers))
+	for _, h := range m.Message.Headers {
+		headers = append(headers, sk.Header{
+			Key:   h.Key,
+			Value: h.Value,
+		})
+	}
+	return headers
+}
+
 func (m Message) Topic() string {
 	return m.Message.Topic
 }
@@ -141,3 +525,19 @@ func (m Message) Partition() int32 {
 func (m Message) Offset() int64 {
 	return m.Message.Offset
 }
+
+func (m Message) Timestamp() time.Time {
+	return m.Message.Time
+}
+
+func (m Message) Ack() {
+	if m.acker != nil {
+		m.acker.Ack()
+	}
+}
+
+func (m Message) Nack() {
+	if m.acker != nil {
+		m.acker.Nack()
+	}
+}
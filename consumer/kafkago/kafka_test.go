@@ -0,0 +1,169 @@
+package kafkago
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+)
+
+func newConsumer(t *testing.T, c sk.ConsumerConfig, options ...OptionFunc) *Handler {
+	t.Helper()
+	h, err := New(c, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Stop)
+	return h
+}
+
+func groupConfig(addrs []string, topics ...string) sk.ConsumerConfig {
+	return sk.ConsumerConfig{
+		Addresses:        addrs,
+		Topics:           topics,
+		GroupID:          fmt.Sprint("group-", time.Now().UnixNano()),
+		StartOffset:      "first",
+		SessionTimeout:   6 * time.Second,
+		RebalanceTimeout: 5 * time.Second,
+	}
+}
+
+func TestGroupResumesFromCommitted(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	t1, t2 := testkit.CreateTopic(t, addrs, 2), testkit.CreateTopic(t, addrs, 1)
+	testkit.Produce(t, addrs, t1, 0, "a0", "a1")
+	testkit.Produce(t, addrs, t1, 1, "b0")
+	testkit.Produce(t, addrs, t2, 0, "c0")
+	cfg := groupConfig(addrs, t1, t2)
+
+	// NOTE: offsets committed in the interval are flushed on Stop
+	h := newConsumer(t, cfg)
+	got := testkit.Values(testkit.Receive(t, h, 4))
+	for _, v := range []string{"a0", "a1", "b0", "c0"} {
+		if !got[v] {
+			t.Fatalf("missing %s in %v", v, got)
+		}
+	}
+	h.Stop()
+
+	testkit.Produce(t, addrs, t1, 1, "b1")
+	h = newConsumer(t, cfg)
+	if msg := testkit.Receive(t, h, 1)[0]; string(msg.Value()) != "b1" || msg.Offset() != 1 {
+		t.Fatalf("got %s at %d, want b1 at 1", msg.Value(), msg.Offset())
+	}
+	testkit.ReceiveNone(t, h, time.Second)
+}
+
+func TestManualAckRedeliversUnacked(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	topic := testkit.CreateTopic(t, addrs, 1)
+	testkit.Produce(t, addrs, topic, 0, "m0", "m1", "m2")
+	cfg := groupConfig(addrs, topic)
+	cfg.ManualAck = true
+	cfg.CommitSync = true
+
+	h := newConsumer(t, cfg)
+	msgs := testkit.Receive(t, h, 3)
+	msgs[0].Ack()
+	msgs[2].Ack()
+	h.Stop()
+
+	h = newConsumer(t, cfg)
+	msgs = testkit.Receive(t, h, 2)
+	if v := string(msgs[0].Value()); v != "m1" {
+		t.Fatalf("got %s, want m1", v)
+	}
+}
+
+// recorded returns options recording assignments of a consumer in a.
+func recorded(a *testkit.Assignments) []OptionFunc {
+	return []OptionFunc{WithOnAssigned(a.OnAssigned), WithOnRevoked(a.OnRevoked)}
+}
+
+func TestGroupRebalance(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	topic := testkit.CreateTopic(t, addrs, 3)
+	cfg := groupConfig(addrs, topic)
+
+	var a1, a2 testkit.Assignments
+	h1 := newConsumer(t, cfg, recorded(&a1)...)
+	testkit.WaitFor(t, "h1 assigned", func() bool { return a1.Len() == 3 })
+
+	h2, err := New(cfg, recorded(&a2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h2.Run(); err != nil {
+		t.Fatal(err)
+	}
+	testkit.WaitFor(t, "partitions spread", func() bool {
+		n1, n2 := a1.Len(), a2.Len()
+		return n1 > 0 && n2 > 0 && n1+n2 == 3
+	})
+
+	for p := 0; p < 3; p++ {
+		testkit.Produce(t, addrs, topic, p, fmt.Sprint("p", p))
+	}
+	got := testkit.Values(append(testkit.Receive(t, h1, a1.Len()), testkit.Receive(t, h2, a2.Len())...))
+	if len(got) != 3 {
+		t.Fatalf("got %v, want a message of every partition", got)
+	}
+
+	h2.Stop()
+	testkit.WaitFor(t, "h1 takes over", func() bool { return a1.Len() == 3 })
+	testkit.ReceiveNone(t, h1, time.Second)
+}
+
+func TestStandalone(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	topic := testkit.CreateTopic(t, addrs, 2)
+	testkit.Produce(t, addrs, topic, 0, "a0")
+	testkit.Produce(t, addrs, topic, 1, "b0", "b1")
+
+	h := newConsumer(t, sk.ConsumerConfig{Addresses: addrs, Topics: []string{topic}, StartOffset: "first"})
+	if got := testkit.Values(testkit.Receive(t, h, 3)); len(got) != 3 {
+		t.Fatalf("got %v", got)
+	}
+
+	h = newConsumer(t, sk.ConsumerConfig{Addresses: addrs, Topics: []string{topic}, Partitions: []int32{1}, StartOffset: "first"})
+	got := testkit.Values(testkit.Receive(t, h, 2))
+	if !got["b0"] || !got["b1"] {
+		t.Fatalf("got %v, want messages of partition 1", got)
+	}
+	testkit.ReceiveNone(t, h, time.Second)
+}
+
+func TestLagOfAllTopics(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	topic := testkit.CreateTopic(t, addrs, 1)
+	testkit.Produce(t, addrs, topic, 0, "m0", "m1", "m2")
+	cfg := groupConfig(addrs, topic)
+	cfg.ManualAck = true
+	cfg.CommitSync = true
+
+	h := newConsumer(t, cfg)
+	testkit.Receive(t, h, 1)[0].Ack()
+	h.Stop()
+
+	r, err := NewLagReader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), testkit.Timeout)
+	defer cancel()
+	// NOTE: the CLI passes an empty slice for all topics
+	lags, err := r.Lag(ctx, cfg.GroupID, []string{}...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lags) != 1 || lags[0].Topic != topic || lags[0].Committed != 1 || lags[0].Lag != 2 {
+		t.Fatalf("got lags %+v, want lag 2 of %s/0", lags, topic)
+	}
+}
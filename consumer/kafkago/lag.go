@@ -0,0 +1,141 @@
+package kafkago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
+)
+
+// LagReader implements sk.LagReader by OffsetFetch and ListOffsets.
+type LagReader struct {
+	client    *kafka.Client
+	transport *kafka.Transport
+}
+
+func NewLagReader(c sk.ConsumerConfig) (*LagReader, error) {
+	if len(c.Addresses) == 0 {
+		return nil, errors.New("addresses is empty")
+	}
+
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	mechanism, err := sasl.KafkaGoMechanism(c.SASL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
+
+	transport := &kafka.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 10 * time.Second,
+		}).DialContext,
+		SASL: mechanism,
+		TLS:  tlsCfg,
+	}
+	return &LagReader{
+		client: &kafka.Client{
+			Addr:      kafka.TCP(c.Addresses...),
+			Transport: transport,
+		},
+		transport: transport,
+	}, nil
+}
+
+func (r *LagReader) Lag(ctx context.Context, groupID string, topics ...string) ([]sk.PartitionLag, error) {
+	// NOTE: kafka-go can not fetch offsets of all topics, so we
+	// fetch every partition and drop topics never committed
+	all := len(topics) == 0
+	if all {
+		// NOTE: an empty topics array requests metadata of no topics
+		topics = nil
+	}
+
+	meta, err := r.client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("metadata: %w", err)
+	}
+	partitions := make(map[string][]int, len(meta.Topics))
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("metadata of topic %s: %w", t.Name, t.Error)
+		}
+		if t.Internal && all {
+			continue
+		}
+		for _, p := range t.Partitions {
+			partitions[t.Name] = append(partitions[t.Name], p.ID)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, nil
+	}
+
+	committed, err := r.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  partitions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("offset fetch: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("offset fetch: %w", committed.Error)
+	}
+
+	offsets := make(map[sk.TopicPartition]int64)
+	used := make(map[string]bool)
+	for topic, ps := range committed.Topics {
+		for _, p := range ps {
+			if p.Error != nil {
+				return nil, fmt.Errorf("offset fetch of %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			offsets[sk.TopicPartition{Topic: topic, Partition: int32(p.Partition)}] = p.CommittedOffset
+			used[topic] = used[topic] || p.CommittedOffset >= 0
+		}
+	}
+
+	requests := make(map[string][]kafka.OffsetRequest, len(partitions))
+	for topic, ps := range partitions {
+		if all && !used[topic] {
+			continue
+		}
+		for _, p := range ps {
+			requests[topic] = append(requests[topic], kafka.LastOffsetOf(p))
+		}
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	ends, err := r.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return nil, fmt.Errorf("list offsets: %w", err)
+	}
+
+	var lags []sk.PartitionLag
+	for topic, ps := range ends.Topics {
+		for _, p := range ps {
+			if p.Error != nil {
+				return nil, fmt.Errorf("list offsets of %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			offset, ok := offsets[sk.TopicPartition{Topic: topic, Partition: int32(p.Partition)}]
+			if !ok {
+				offset = -1
+			}
+			lags = append(lags, sk.NewPartitionLag(topic, int32(p.Partition), offset, p.LastOffset))
+		}
+	}
+	sk.SortLags(lags)
+	return lags, nil
+}
+
+func (r *LagReader) Close() error {
+	r.transport.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,74 @@
+package kafkago
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+)
+
+// reportMetrics reports stats every sk.MetricsInterval until h is stopped.
+func (h *Handler) reportMetrics() {
+	ticker := time.NewTicker(sk.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.report()
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// closeReader closes reader of a partition, its last stats are kept
+// for the next report.
+func (h *Handler) closeReader(reader *kafka.Reader) {
+	if h.metrics != nil {
+		stats := reader.Stats()
+		h.mu.Lock()
+		h.closed = append(h.closed, stats)
+		h.mu.Unlock()
+	}
+	_ = reader.Close()
+}
+
+// report reports stats of running partition readers and the ones closed
+// since the last report.
+func (h *Handler) report() {
+	h.mu.Lock()
+	all := h.closed
+	h.closed = nil
+	for _, r := range h.readers {
+		all = append(all, r.Stats())
+	}
+	h.mu.Unlock()
+
+	// NOTE: averages of readers are weighted by their fetches
+	var (
+		cs       sk.ConsumerStats
+		messages float64
+		wait     time.Duration
+	)
+	for _, s := range all {
+		cs.Fetches += s.Fetches
+		cs.Errors += s.Errors
+		messages += float64(s.FetchSize.Avg * s.Fetches)
+		wait += s.WaitTime.Avg * time.Duration(s.Fetches)
+
+		partition, _ := strconv.Atoi(s.Partition)
+		h.metrics.ReportPartition(sk.PartitionStats{
+			Topic:     s.Topic,
+			Partition: int32(partition),
+			Messages:  s.Messages,
+			Bytes:     s.Bytes,
+			Lag:       s.Lag,
+		})
+	}
+	if cs.Fetches > 0 {
+		cs.BatchSize = messages / float64(cs.Fetches)
+		cs.Latency = wait / time.Duration(cs.Fetches)
+	}
+	h.metrics.ReportConsumer(cs)
+}
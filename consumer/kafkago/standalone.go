@@ -0,0 +1,93 @@
+package kafkago
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+)
+
+// runStandalone reads partitions without a consumer group,
+// partitions start from the start position of config.
+func (h *Handler) runStandalone() error {
+	tps, err := h.lookupPartitions(h.ctx)
+	if err != nil {
+		return err
+	}
+
+	offset := h.groupConfig.StartOffset
+	if offset == 0 {
+		offset = kafka.FirstOffset
+	}
+
+	if h.metrics != nil {
+		go h.reportMetrics()
+	}
+
+	go func() {
+		defer close(h.msgChan)
+		if h.batchChan != nil {
+			defer close(h.batchChan)
+		}
+		if h.metrics != nil {
+			defer h.report()
+		}
+
+		if h.onAssigned != nil {
+			h.onAssigned(tps)
+		}
+		var wg sync.WaitGroup
+		for _, tp := range tps {
+			tp := tp
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.consumePartition(h.ctx, nil, tp.Topic, int(tp.Partition), offset)
+			}()
+		}
+		wg.Wait()
+		if h.onRevoked != nil {
+			h.onRevoked(tps)
+		}
+	}()
+
+	return nil
+}
+
+// lookupPartitions returns the configured partitions of every topic,
+// or all of them if none is configured.
+func (h *Handler) lookupPartitions(ctx context.Context) ([]sk.TopicPartition, error) {
+	var tps []sk.TopicPartition
+	for _, topic := range h.groupConfig.Topics {
+		if len(h.partitions) > 0 {
+			for _, p := range h.partitions {
+				tps = append(tps, sk.TopicPartition{Topic: topic, Partition: p})
+			}
+			continue
+		}
+
+		dialer := h.readerConfig.Dialer
+		if dialer == nil {
+			dialer = kafka.DefaultDialer
+		}
+		var (
+			partitions []kafka.Partition
+			err        error
+		)
+		for _, broker := range h.readerConfig.Brokers {
+			partitions, err = dialer.LookupPartitions(ctx, "tcp", broker, topic)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("lookup partitions of %s: %w", topic, err)
+		}
+		for _, p := range partitions {
+			tps = append(tps, sk.TopicPartition{Topic: topic, Partition: int32(p.ID)})
+		}
+	}
+	return tps, nil
+}
@@ -0,0 +1,42 @@
+package sarama
+
+import (
+	sk "github.com/sko00o/kafka"
+)
+
+// Backend is the name of this consumer in the backend registry.
+const Backend = "sarama"
+
+func init() {
+	sk.RegisterConsumer(Backend, func(c sk.ConsumerConfig, o sk.Options) (sk.Consumer, error) {
+		var options []OptionFunc
+		if o.Logger != nil {
+			options = append(options, WithLogger(o.Logger))
+		}
+		if o.Metrics != nil {
+			options = append(options, WithMetrics(o.Metrics))
+		}
+		if o.TokenProvider != nil {
+			options = append(options, WithTokenProvider(o.TokenProvider))
+		}
+		if o.OnAssigned != nil {
+			options = append(options, WithOnAssigned(o.OnAssigned))
+		}
+		if o.OnRevoked != nil {
+			options = append(options, WithOnRevoked(o.OnRevoked))
+		}
+
+		h, err := New(c, options...)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	})
+	sk.RegisterLag(Backend, func(c sk.ConsumerConfig) (sk.LagReader, error) {
+		r, err := NewLagReader(c)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+}
@@ -4,34 +4,86 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/Shopify/sarama"
 	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/ack"
+	"github.com/sko00o/kafka/internal/batch"
+	"github.com/sko00o/kafka/internal/pause"
+	"github.com/sko00o/kafka/internal/seek"
+	"github.com/sko00o/kafka/sasl"
 )
 
+const consumeRetryBackoff = time.Second
+
 type Handler struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
+	client  sarama.Client
 	group   sarama.ConsumerGroup
 	topics  []string
 	msgChan chan sk.Message
 	log     Logger
+	wg      sync.WaitGroup
+
+	// group-less mode if group is nil
+	consumer sarama.Consumer
+	// seeker consumes claimed partitions moved by Seek
+	seeker     sarama.Consumer
+	partitions []int32
+	initial    int64
+	pauser     interface {
+		Pause(map[string][]int32)
+		Resume(map[string][]int32)
+	}
+
+	tokenProvider sasl.TokenProvider
+
+	metrics sk.Metrics
+	stats   stats
+
+	onAssigned func([]sk.TopicPartition)
+	onRevoked  func([]sk.TopicPartition)
+
+	manualAck  bool
+	commitSync bool
+
+	batchChan chan sk.Batch
+	limits    batch.Limits
+
+	resets *seek.Resets
+	paused pause.Set
+	mu     sync.Mutex
+	claims map[string][]int32
+	// seeks are claims to be signaled of pending resets
+	seeks map[sk.TopicPartition]*seekableClaim
+	// restart restarts partitions of group-less mode, so pending resets get applied
+	restart context.CancelFunc
 }
 
 func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	h := &Handler{
-		ctx:    ctx,
-		cancel: cancel,
-		topics: c.Topics,
+		ctx:        ctx,
+		cancel:     cancel,
+		topics:     c.Topics,
+		partitions: c.Partitions,
+		manualAck:  c.ManualAck,
+		commitSync: c.CommitSync,
+		seeks:      make(map[sk.TopicPartition]*seekableClaim),
 	}
 	if cnt := int(c.WorkerCnt); cnt > 0 {
 		h.msgChan = make(chan sk.Message, cnt)
 	} else {
 		h.msgChan = make(chan sk.Message, 1)
 	}
+	if c.Batch != nil {
+		h.batchChan = make(chan sk.Batch, cap(h.msgChan))
+		h.limits = batch.NewLimits(c.Batch)
+	}
 
 	// NOTE: we need to set logger in kafka reader, so we call OptionFunc here
 	for _, option := range options {
@@ -50,8 +102,8 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 		cfg.Version = version
 	}
 
-	if c.GroupID == "" {
-		return nil, errors.New("group_id is empty")
+	if len(c.Topics) == 0 {
+		return nil, errors.New("topics is empty")
 	}
 
 	if v := c.MinBytes; v > 0 {
@@ -60,6 +112,13 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 	if v := c.MaxBytes; v > 0 {
 		cfg.Consumer.Fetch.Max = int32(v)
 	}
+	switch v := c.IsolationLevel; v {
+	case "", "read_uncommitted":
+	case "read_committed":
+		cfg.Consumer.IsolationLevel = sarama.ReadCommitted
+	default:
+		return nil, fmt.Errorf("isolation_level %s not support", v)
+	}
 
 	if !c.CommitSync {
 		cfg.Consumer.Offsets.AutoCommit.Interval = 2 * time.Second
@@ -67,13 +126,14 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 			cfg.Consumer.Offsets.AutoCommit.Interval = v
 		}
 	}
-	if v := c.StartOffset; v != "" {
-		switch strings.ToLower(v) {
-		case "first":
-			cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
-		case "last":
-			cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
-		}
+	resets, err := seek.New(c)
+	if err != nil {
+		return nil, err
+	}
+	h.resets = resets
+	if v := resets.Initial; v != 0 {
+		// NOTE: same values as sarama.OffsetOldest and sarama.OffsetNewest
+		cfg.Consumer.Offsets.Initial = v
 	}
 	if v := c.SessionTimeout; v != 0 {
 		cfg.Consumer.Group.Session.Timeout = v
@@ -83,19 +143,61 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 	}
 	cfg.Consumer.Return.Errors = c.EnableErrors
 
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	if tlsCfg != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsCfg
+	}
+	if err := sasl.ConfigureSarama(cfg, c.SASL, h.tokenProvider); err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("config validate: %w", err)
 	}
 
-	group, err := sarama.NewConsumerGroup(c.Addresses, c.GroupID, cfg)
+	client, err := sarama.NewClient(c.Addresses, cfg)
 	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+	h.client = client
+	h.initial = cfg.Consumer.Offsets.Initial
+	h.stats.meters.Registry = cfg.MetricRegistry
+	h.stats.partitions = make(map[sk.TopicPartition]*sk.PartitionStats)
+
+	if c.GroupID == "" {
+		// NOTE: no group_id means group-less mode, offsets are not committed
+		consumer, err := sarama.NewConsumerFromClient(client)
+		if err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("new consumer: %w", err)
+		}
+		h.consumer = consumer
+		h.pauser = consumer
+		return h, nil
+	}
+
+	group, err := sarama.NewConsumerGroupFromClient(c.GroupID, client)
+	if err != nil {
+		_ = client.Close()
 		return nil, fmt.Errorf("new consumer group: %w", err)
 	}
+	seeker, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		_ = group.Close()
+		_ = client.Close()
+		return nil, fmt.Errorf("new consumer: %w", err)
+	}
+	h.seeker = seeker
 
 	if c.EnableErrors {
 		// Track errors
 		go func() {
 			for err := range group.Errors() {
+				h.countError()
 				if h.log != nil {
 					h.log.Errorf("consumer group: %v", err)
 				}
@@ -103,29 +205,41 @@ func New(c sk.ConsumerConfig, options ...OptionFunc) (*Handler, error) {
 		}()
 	}
 	h.group = group
+	h.pauser = group
 
 	return h, nil
 }
 
 func (h *Handler) Run() error {
+	if h.metrics != nil {
+		go h.reportMetrics()
+	}
+	if h.group == nil {
+		return h.runStandalone()
+	}
+
 	go func() {
 		defer close(h.msgChan)
+		if h.batchChan != nil {
+			defer close(h.batchChan)
+		}
 
 		for {
-			handler := &consumeHandler{
-				msgChan: h.msgChan,
+			err := h.group.Consume(h.ctx, h.topics, consumeHandler{h})
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) || h.ctx.Err() != nil {
+				// reader closed
+				return
 			}
-			if err := h.group.Consume(h.ctx, h.topics, handler); err != nil {
-				if errors.Is(err, sarama.ErrClosedConsumerGroup) ||
-					errors.Is(err, context.Canceled) {
-					// reader closed
-					return
-				}
-
+			if err != nil && !errors.Is(err, context.Canceled) {
 				if h.log != nil {
-					h.log.Errorf("stop reader: %v", err)
+					h.log.Errorf("stop reader: %v, retry in %s", err, consumeRetryBackoff)
+				}
+				// NOTE: every Consume rejoins, so the group is not rebalanced in a hot loop
+				select {
+				case <-time.After(consumeRetryBackoff):
+				case <-h.ctx.Done():
+					return
 				}
-				continue
 			}
 		}
 	}()
@@ -135,39 +249,372 @@ func (h *Handler) Run() error {
 
 func (h *Handler) Stop() {
 	h.cancel()
-	if err := h.group.Close(); err != nil {
+	if h.group != nil {
+		if err := h.group.Close(); err != nil {
+			if h.log != nil {
+				h.log.Errorf("stop reader: %v", err)
+			}
+		}
+		if err := h.seeker.Close(); err != nil {
+			if h.log != nil {
+				h.log.Errorf("stop consumer: %v", err)
+			}
+		}
+	} else {
+		// NOTE: partition consumers must be closed first
+		h.wg.Wait()
+		if err := h.consumer.Close(); err != nil {
+			if h.log != nil {
+				h.log.Errorf("stop consumer: %v", err)
+			}
+		}
+	}
+	if h.metrics != nil {
+		h.report()
+	}
+	if err := h.client.Close(); err != nil {
 		if h.log != nil {
-			h.log.Errorf("stop reader: %v", err)
+			h.log.Errorf("stop client: %v", err)
 		}
 	}
 }
 
+// Seek implements sk.Seeker, claimed partitions are moved within the
+// current session, the others once claimed.
+func (h *Handler) Seek(offsets ...sk.PartitionOffset) error {
+	for _, o := range offsets {
+		if err := seek.Validate(o); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	restart := false
+	for _, o := range offsets {
+		tp := sk.TopicPartition{Topic: o.Topic, Partition: o.Partition}
+		h.resets.Seek(tp, seek.Position{Offset: o.Offset})
+		restart = h.seekLocked(tp) || restart
+	}
+	if restart && h.restart != nil {
+		h.restart()
+	}
+	return nil
+}
+
+// SeekTime implements sk.Seeker.
+func (h *Handler) SeekTime(t time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	restart := false
+	for topic, partitions := range h.claims {
+		for _, p := range partitions {
+			tp := sk.TopicPartition{Topic: topic, Partition: p}
+			h.resets.Seek(tp, seek.Position{Time: t})
+			restart = h.seekLocked(tp) || restart
+		}
+	}
+	if restart && h.restart != nil {
+		h.restart()
+	}
+	return nil
+}
+
+// seekLocked signals the claim of tp of its pending reset, it returns true
+// if tp is consumed in group-less mode, which is to be restarted instead.
+func (h *Handler) seekLocked(tp sk.TopicPartition) bool {
+	if h.group == nil {
+		for _, p := range h.claims[tp.Topic] {
+			if p == tp.Partition {
+				return true
+			}
+		}
+		return false
+	}
+	if c, ok := h.seeks[tp]; ok {
+		c.signal()
+	}
+	return false
+}
+
+// Pause implements sk.Pauser.
+func (h *Handler) Pause(partitions ...sk.TopicPartition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.paused.Pause(partitions...)
+	h.pauser.Pause(partitionMap(partitions))
+	if h.seeker != nil {
+		h.seeker.Pause(partitionMap(partitions))
+	}
+}
+
+func (h *Handler) Resume(partitions ...sk.TopicPartition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.paused.Resume(partitions...)
+	h.pauser.Resume(partitionMap(partitions))
+	if h.seeker != nil {
+		h.seeker.Resume(partitionMap(partitions))
+	}
+}
+
+func (h *Handler) Paused() []sk.TopicPartition {
+	return h.paused.List()
+}
+
+func partitionMap(tps []sk.TopicPartition) map[string][]int32 {
+	m := make(map[string][]int32)
+	for _, tp := range tps {
+		m[tp.Topic] = append(m[tp.Topic], tp.Partition)
+	}
+	return m
+}
+
+// resolve turns p into an absolute offset of tp.
+func (h *Handler) resolve(tp sk.TopicPartition, p seek.Position) (int64, error) {
+	at := p.Offset
+	if !p.Time.IsZero() {
+		at = p.Time.UnixMilli()
+	} else if at >= 0 {
+		return at, nil
+	}
+
+	offset, err := h.client.GetOffset(tp.Topic, tp.Partition, at)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		// NOTE: no message after the time
+		return h.client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetNewest)
+	}
+	return offset, nil
+}
+
 func (h *Handler) Receive() <-chan sk.Message {
 	return h.msgChan
 }
 
+// ReceiveBatch returns nil if batch mode is disabled.
+func (h *Handler) ReceiveBatch() <-chan sk.Batch {
+	return h.batchChan
+}
+
 type consumeHandler struct {
-	msgChan chan sk.Message
+	*Handler
 }
 
-func (consumeHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (consumeHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
+func (h consumeHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.mu.Lock()
+	h.claims = sess.Claims()
+	h.mu.Unlock()
+
+	tps := topicPartitions(sess.Claims())
+	for _, tp := range tps {
+		p, ok := h.resets.Take(tp)
+		if !ok {
+			continue
+		}
+		offset, err := h.resolve(tp, p)
+		if err != nil {
+			// NOTE: consume from the committed offset, the claim retries it
+			h.resets.Seek(tp, p)
+			if h.log != nil {
+				h.log.Errorf("seek %s/%d: %v", tp.Topic, tp.Partition, err)
+			}
+			continue
+		}
+		// NOTE: reset only moves backwards and mark only forwards
+		sess.ResetOffset(tp.Topic, tp.Partition, offset, "")
+		sess.MarkOffset(tp.Topic, tp.Partition, offset, "")
+	}
+
+	if h.onAssigned != nil {
+		h.onAssigned(tps)
+	}
+	return nil
+}
+
+func (h consumeHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	h.mu.Lock()
+	h.claims = nil
+	h.mu.Unlock()
+
+	if h.onRevoked != nil {
+		h.onRevoked(topicPartitions(sess.Claims()))
+	}
+	return nil
+}
+
+func topicPartitions(claims map[string][]int32) []sk.TopicPartition {
+	var tps []sk.TopicPartition
+	for topic, partitions := range claims {
+		for _, p := range partitions {
+			tps = append(tps, sk.TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+	sort.Slice(tps, func(i, j int) bool {
+		if tps[i].Topic != tps[j].Topic {
+			return tps[i].Topic < tps[j].Topic
+		}
+		return tps[i].Partition < tps[j].Partition
+	})
+	return tps
+}
 func (h consumeHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// NOTE: partition consumers of a new session start unpaused
+	h.mu.Lock()
+	if h.paused.IsPaused(sk.TopicPartition{Topic: claim.Topic(), Partition: claim.Partition()}) {
+		h.group.Pause(map[string][]int32{claim.Topic(): {claim.Partition()}})
+	}
+	h.mu.Unlock()
+
+	claim = h.seekable(sess, claim)
+	if h.batchChan != nil {
+		return h.consumeClaimBatch(sess, claim)
+	}
+	if h.manualAck {
+		return h.consumeClaimManualAck(sess, claim)
+	}
+
+	// NOTE: a blocked send must not hold the session, or rebalance and Stop wait for the reader
 	for msg := range claim.Messages() {
-		h.msgChan <- Message{msg}
+		h.observe(msg, claim.HighWaterMarkOffset())
+		select {
+		case h.msgChan <- Message{ConsumerMessage: msg}:
+		case <-sess.Context().Done():
+			return nil
+		}
 		sess.MarkMessage(msg, "")
 	}
 	return nil
 }
 
+func (h consumeHandler) consumeClaimManualAck(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// NOTE: the window lives as long as the claim, acks arriving after
+	// a rebalance only mark offsets of the released session.
+	window := new(ack.Window)
+	commit := h.commitFunc(sess, claim)
+
+	for msg := range claim.Messages() {
+		h.observe(msg, claim.HighWaterMarkOffset())
+		select {
+		case h.msgChan <- Message{
+			ConsumerMessage: msg,
+			acker:           window.Track(msg.Offset, commit),
+		}:
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (h consumeHandler) commitFunc(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) func(int64) {
+	return func(next int64) {
+		sess.MarkOffset(claim.Topic(), claim.Partition(), next, "")
+		if h.commitSync {
+			sess.Commit()
+		}
+	}
+}
+
+func (h consumeHandler) consumeClaimBatch(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var (
+		window *ack.Window
+		commit func(int64)
+	)
+	if h.manualAck {
+		window = new(ack.Window)
+		commit = h.commitFunc(sess, claim)
+	}
+	message := func(msg *sarama.ConsumerMessage) Message {
+		h.observe(msg, claim.HighWaterMarkOffset())
+		m := Message{ConsumerMessage: msg}
+		if window != nil {
+			m.acker = window.Track(msg.Offset, commit)
+		}
+		return m
+	}
+
+	h.collectBatches(sess.Context(), claim.Messages(), message, func(last *sarama.ConsumerMessage) {
+		if !h.manualAck {
+			sess.MarkMessage(last, "")
+		}
+	})
+	return nil
+}
+
+// collectBatches delivers msgs of a single partition in batches until msgs
+// is closed or ctx is done, delivered is called after every batch.
+func (h *Handler) collectBatches(ctx context.Context, msgs <-chan *sarama.ConsumerMessage, message func(*sarama.ConsumerMessage) Message, delivered func(last *sarama.ConsumerMessage)) {
+	for msg := range msgs {
+		b := batch.New(msg.Topic, msg.Partition)
+		b.Add(message(msg))
+		last, open := msg, true
+
+		timer := time.NewTimer(h.limits.MaxWait)
+	collect:
+		for !b.Full(h.limits) {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					open = false
+					break collect
+				}
+				b.Add(message(msg))
+				last = msg
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		select {
+		case h.batchChan <- b:
+		case <-ctx.Done():
+			return
+		}
+		delivered(last)
+		if !open {
+			return
+		}
+	}
+}
+
 type Message struct {
 	*sarama.ConsumerMessage
+	acker *ack.Handle
+}
+
+func (m Message) Key() []byte {
+	return m.ConsumerMessage.Key
 }
 
 func (m Message) Value() []byte {
 	return m.ConsumerMessage.Value
 }
 
+func (m Message) Headers() []sk.Header {
+	if len(m.ConsumerMessage.Headers) == 0 {
+		return nil
+	}
+	headers := make([]sk.Header, 0, len(m.ConsumerMessage.Headers))
+	for _, h := range m.ConsumerMessage.Headers {
+		if h == nil {
+			continue
+		}
+		headers = append(headers, sk.Header{
+			Key:   string(h.Key),
+			Value: h.Value,
+		})
+	}
+	return headers
+}
+
 func (m Message) Topic() string {
 	return m.ConsumerMessage.Topic
 }
@@ -179,3 +626,19 @@ func (m Message) Partition() int32 {
 func (m Message) Offset() int64 {
 	return m.ConsumerMessage.Offset
 }
+
+func (m Message) Timestamp() time.Time {
+	return m.ConsumerMessage.Timestamp
+}
+
+func (m Message) Ack() {
+	if m.acker != nil {
+		m.acker.Ack()
+	}
+}
+
+func (m Message) Nack() {
+	if m.acker != nil {
+		m.acker.Nack()
+	}
+}
@@ -0,0 +1,72 @@
+package sarama
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+)
+
+// version is the kafka version of the brokers tests run against.
+const version = "2.5.1"
+
+func TestSeekWithinSession(t *testing.T) {
+	addrs := testkit.Brokers(t)
+	topic := testkit.CreateTopic(t, addrs, 1)
+	testkit.Produce(t, addrs, topic, 0, "m0", "m1", "m2")
+
+	var assigned, revoked int32
+	h, err := New(sk.ConsumerConfig{
+		Addresses:   addrs,
+		Topics:      []string{topic},
+		GroupID:     fmt.Sprint("group-", time.Now().UnixNano()),
+		StartOffset: "first",
+		Version:     version,
+	},
+		WithOnAssigned(func([]sk.TopicPartition) { atomic.AddInt32(&assigned, 1) }),
+		WithOnRevoked(func([]sk.TopicPartition) { atomic.AddInt32(&revoked, 1) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Stop()
+	testkit.Receive(t, h, 3)
+
+	if err := h.Seek(sk.PartitionOffset{Topic: topic, Partition: 0, Offset: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := testkit.Receive(t, h, 1)[0]; string(msg.Value()) != "m1" || msg.Offset() != 1 {
+		t.Fatalf("got %s at %d after seek, want m1 at 1", msg.Value(), msg.Offset())
+	}
+
+	// NOTE: a second seek replaces the partition consumer of the first
+	if err := h.SeekTime(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: messages dispatched before the seek are still delivered
+	for v := ""; v != "m0"; {
+		v = string(testkit.Receive(t, h, 1)[0].Value())
+	}
+	for _, want := range []string{"m1", "m2"} {
+		if v := string(testkit.Receive(t, h, 1)[0].Value()); v != want {
+			t.Fatalf("got %s after seek time, want %s", v, want)
+		}
+	}
+	testkit.Produce(t, addrs, topic, 0, "m3")
+	if v := string(testkit.Receive(t, h, 1)[0].Value()); v != "m3" {
+		t.Fatalf("got %s, want m3", v)
+	}
+
+	if n := atomic.LoadInt32(&revoked); n != 0 {
+		t.Fatalf("seek rebalanced the session %d times", n)
+	}
+	if n := atomic.LoadInt32(&assigned); n != 1 {
+		t.Fatalf("got %d assignments, want 1", n)
+	}
+}
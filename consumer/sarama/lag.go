@@ -0,0 +1,103 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
+)
+
+// LagReader implements sk.LagReader by ClusterAdmin and Client.
+type LagReader struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+func NewLagReader(c sk.ConsumerConfig) (*LagReader, error) {
+	if len(c.Addresses) == 0 {
+		return nil, errors.New("addresses is empty")
+	}
+
+	cfg := sarama.NewConfig()
+	if v := c.Version; v != "" {
+		version, err := sarama.ParseKafkaVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("set kafka version %s: %w", v, err)
+		}
+		cfg.Version = version
+	}
+
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	if tlsCfg != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsCfg
+	}
+	if err := sasl.ConfigureSarama(cfg, c.SASL, nil); err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
+
+	client, err := sarama.NewClient(c.Addresses, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("new cluster admin: %w", err)
+	}
+	return &LagReader{client: client, admin: admin}, nil
+}
+
+// NOTE: sarama does not take a context, ctx is only checked between requests.
+func (r *LagReader) Lag(ctx context.Context, groupID string, topics ...string) ([]sk.PartitionLag, error) {
+	// NOTE: nil fetches offsets of all topics committed by the group
+	var request map[string][]int32
+	if len(topics) != 0 {
+		request = make(map[string][]int32, len(topics))
+		for _, topic := range topics {
+			partitions, err := r.client.Partitions(topic)
+			if err != nil {
+				return nil, fmt.Errorf("partitions of topic %s: %w", topic, err)
+			}
+			request[topic] = partitions
+		}
+	}
+
+	resp, err := r.admin.ListConsumerGroupOffsets(groupID, request)
+	if err != nil {
+		return nil, fmt.Errorf("list consumer group offsets: %w", err)
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, fmt.Errorf("list consumer group offsets: %w", resp.Err)
+	}
+
+	var lags []sk.PartitionLag
+	for topic, blocks := range resp.Blocks {
+		for partition, block := range blocks {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("offset fetch of %s/%d: %w", topic, partition, block.Err)
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			end, err := r.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("get offset of %s/%d: %w", topic, partition, err)
+			}
+			lags = append(lags, sk.NewPartitionLag(topic, partition, block.Offset, end))
+		}
+	}
+	sk.SortLags(lags)
+	return lags, nil
+}
+
+func (r *LagReader) Close() error {
+	// NOTE: the admin closes the client it was created from
+	return r.admin.Close()
+}
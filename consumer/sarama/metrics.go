@@ -0,0 +1,81 @@
+package sarama
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/gometrics"
+)
+
+// stats are collected between reports, fetches and averages are read
+// from the go-metrics registry of sarama.
+type stats struct {
+	meters gometrics.Meters
+	errors int64
+
+	mu         sync.Mutex
+	partitions map[sk.TopicPartition]*sk.PartitionStats
+}
+
+// observe counts msg of a partition with high water mark hwm.
+func (h *Handler) observe(msg *sarama.ConsumerMessage, hwm int64) {
+	if h.metrics == nil {
+		return
+	}
+
+	tp := sk.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+	s, ok := h.stats.partitions[tp]
+	if !ok {
+		s = &sk.PartitionStats{Topic: msg.Topic, Partition: msg.Partition}
+		h.stats.partitions[tp] = s
+	}
+	s.Messages++
+	s.Bytes += int64(len(msg.Key) + len(msg.Value))
+	s.Lag = hwm - msg.Offset - 1
+	if s.Lag < 0 {
+		s.Lag = 0
+	}
+}
+
+func (h *Handler) countError() {
+	if h.metrics != nil {
+		atomic.AddInt64(&h.stats.errors, 1)
+	}
+}
+
+// reportMetrics reports stats every sk.MetricsInterval until h is stopped.
+func (h *Handler) reportMetrics() {
+	ticker := time.NewTicker(sk.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.report()
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) report() {
+	registry := h.client.Config().MetricRegistry
+	h.metrics.ReportConsumer(sk.ConsumerStats{
+		Fetches:   h.stats.meters.Delta("consumer-fetch-rate"),
+		Errors:    atomic.SwapInt64(&h.stats.errors, 0),
+		BatchSize: gometrics.Mean(registry, "consumer-batch-size"),
+		Latency:   time.Duration(gometrics.Mean(registry, "request-latency-in-ms") * float64(time.Millisecond)),
+	})
+
+	h.stats.mu.Lock()
+	partitions := h.stats.partitions
+	h.stats.partitions = make(map[sk.TopicPartition]*sk.PartitionStats)
+	h.stats.mu.Unlock()
+	for _, s := range partitions {
+		h.metrics.ReportPartition(*s)
+	}
+}
@@ -1,5 +1,10 @@
 package sarama
 
+import (
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
+)
+
 type OptionFunc func(*Handler) error
 
 func WithLogger(log Logger) OptionFunc {
@@ -8,3 +13,39 @@ func WithLogger(log Logger) OptionFunc {
 		return nil
 	}
 }
+
+// WithMetrics reports stats to m every sk.MetricsInterval and on stop,
+// errors of the group are counted only if enable_errors is set.
+func WithMetrics(m sk.Metrics) OptionFunc {
+	return func(h *Handler) error {
+		h.metrics = m
+		return nil
+	}
+}
+
+// WithOnAssigned sets the callback of partitions assigned by a rebalance,
+// it is called before any message of them is dispatched.
+func WithOnAssigned(fn func([]sk.TopicPartition)) OptionFunc {
+	return func(h *Handler) error {
+		h.onAssigned = fn
+		return nil
+	}
+}
+
+// WithOnRevoked sets the callback of partitions revoked by a rebalance
+// or stop, it is called before their final offset commit.
+func WithOnRevoked(fn func([]sk.TopicPartition)) OptionFunc {
+	return func(h *Handler) error {
+		h.onRevoked = fn
+		return nil
+	}
+}
+
+// WithTokenProvider sets the token provider of the oauthbearer mechanism,
+// it overrides the token settings of config.
+func WithTokenProvider(p sasl.TokenProvider) OptionFunc {
+	return func(h *Handler) error {
+		h.tokenProvider = p
+		return nil
+	}
+}
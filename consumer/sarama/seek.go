@@ -0,0 +1,161 @@
+package sarama
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+)
+
+const seekRetryBackoff = time.Second
+
+// seekableClaim is a claim moved by Seek within its session, its messages
+// are switched to a partition consumer of the sought offset.
+type seekableClaim struct {
+	sarama.ConsumerGroupClaim
+	msgs  chan *sarama.ConsumerMessage
+	seeks chan struct{}
+
+	mu sync.Mutex
+	pc sarama.PartitionConsumer
+}
+
+func (c *seekableClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.msgs
+}
+
+func (c *seekableClaim) HighWaterMarkOffset() int64 {
+	c.mu.Lock()
+	pc := c.pc
+	c.mu.Unlock()
+	if pc != nil {
+		return pc.HighWaterMarkOffset()
+	}
+	return c.ConsumerGroupClaim.HighWaterMarkOffset()
+}
+
+// signal notifies the claim of a pending reset.
+func (c *seekableClaim) signal() {
+	select {
+	case c.seeks <- struct{}{}:
+	default:
+		// NOTE: a signal is pending already
+	}
+}
+
+// swap replaces the partition consumer of the claim, the previous one is
+// closed first, as sarama consumes a partition only once.
+func (c *seekableClaim) swap(pc sarama.PartitionConsumer) {
+	c.mu.Lock()
+	prev := c.pc
+	c.pc = nil
+	c.mu.Unlock()
+	if prev != nil {
+		_ = prev.Close()
+	}
+
+	c.mu.Lock()
+	c.pc = pc
+	c.mu.Unlock()
+}
+
+// seekable wraps claim, its messages are forwarded until the session ends.
+func (h consumeHandler) seekable(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) *seekableClaim {
+	tp := sk.TopicPartition{Topic: claim.Topic(), Partition: claim.Partition()}
+	c := &seekableClaim{
+		ConsumerGroupClaim: claim,
+		msgs:               make(chan *sarama.ConsumerMessage),
+		seeks:              make(chan struct{}, 1),
+	}
+	h.mu.Lock()
+	h.seeks[tp] = c
+	h.mu.Unlock()
+
+	// NOTE: a reset failed in Setup is still pending
+	c.signal()
+	go func() {
+		defer close(c.msgs)
+		defer func() {
+			h.mu.Lock()
+			delete(h.seeks, tp)
+			h.mu.Unlock()
+			c.swap(nil)
+		}()
+
+		in := claim.Messages()
+		for {
+			select {
+			case msg, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case c.msgs <- msg:
+				case <-sess.Context().Done():
+					return
+				}
+			case <-c.seeks:
+				moved, err := h.seekClaim(sess, c, tp)
+				if err != nil {
+					if h.log != nil {
+						h.log.Errorf("seek %s/%d: %v, retry in %s", tp.Topic, tp.Partition, err, seekRetryBackoff)
+					}
+					time.AfterFunc(seekRetryBackoff, c.signal)
+				}
+				if moved {
+					// NOTE: the claim stops fetching once its buffer is full,
+					// sarama drains it when the session ends
+					in = nil
+					if c.pc != nil {
+						in = c.pc.Messages()
+					}
+				}
+			case <-sess.Context().Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// seekClaim applies the pending reset of tp in sess, it returns true if
+// the partition consumer of c is replaced. A failed reset is kept.
+func (h consumeHandler) seekClaim(sess sarama.ConsumerGroupSession, c *seekableClaim, tp sk.TopicPartition) (bool, error) {
+	p, ok := h.resets.Take(tp)
+	if !ok {
+		return false, nil
+	}
+	offset, err := h.resolve(tp, p)
+	if err != nil {
+		h.resets.Seek(tp, p)
+		return false, err
+	}
+
+	c.swap(nil)
+	pc, err := h.seeker.ConsumePartition(tp.Topic, tp.Partition, offset)
+	if err != nil {
+		// NOTE: nothing is consumed until the retry
+		h.resets.Seek(tp, p)
+		return true, err
+	}
+	go func() {
+		for err := range pc.Errors() {
+			h.countError()
+			if h.log != nil {
+				h.log.Errorf("consume partition %s/%d: %v", tp.Topic, tp.Partition, err)
+			}
+		}
+	}()
+	h.mu.Lock()
+	if h.paused.IsPaused(tp) {
+		pc.Pause()
+	}
+	h.mu.Unlock()
+	c.swap(pc)
+
+	// NOTE: reset only moves backwards and mark only forwards
+	sess.ResetOffset(tp.Topic, tp.Partition, offset, "")
+	sess.MarkOffset(tp.Topic, tp.Partition, offset, "")
+	return true, nil
+}
@@ -0,0 +1,157 @@
+package sarama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+)
+
+// runStandalone reads partitions without a consumer group, all of them
+// are restarted by Seek, so pending positions get applied.
+func (h *Handler) runStandalone() error {
+	tps, err := h.lookupPartitions()
+	if err != nil {
+		return err
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer close(h.msgChan)
+		if h.batchChan != nil {
+			defer close(h.batchChan)
+		}
+
+		if h.onAssigned != nil {
+			h.onAssigned(tps)
+		}
+
+		next := make([]int64, len(tps))
+		for i := range next {
+			next[i] = h.initial
+		}
+		for h.ctx.Err() == nil {
+			ctx, cancel := context.WithCancel(h.ctx)
+			h.mu.Lock()
+			h.restart = cancel
+			h.claims = partitionMap(tps)
+			h.mu.Unlock()
+
+			var wg sync.WaitGroup
+			for i, tp := range tps {
+				i, tp := i, tp
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					next[i] = h.consumePartition(ctx, tp, next[i])
+				}()
+			}
+			wg.Wait()
+
+			if ctx.Err() == nil {
+				// NOTE: every partition failed, retry later
+				select {
+				case <-time.After(time.Second):
+				case <-h.ctx.Done():
+				}
+			}
+			cancel()
+		}
+
+		if h.onRevoked != nil {
+			h.onRevoked(tps)
+		}
+	}()
+
+	return nil
+}
+
+// consumePartition reads tp from offset until ctx is done,
+// it returns the offset to continue from.
+func (h *Handler) consumePartition(ctx context.Context, tp sk.TopicPartition, offset int64) int64 {
+	if p, ok := h.resets.Take(tp); ok {
+		resolved, err := h.resolve(tp, p)
+		if err != nil {
+			// NOTE: keep it for the next restart
+			h.resets.Seek(tp, p)
+			if h.log != nil {
+				h.log.Errorf("seek %s/%d: %v", tp.Topic, tp.Partition, err)
+			}
+			return offset
+		}
+		offset = resolved
+	}
+
+	pc, err := h.consumer.ConsumePartition(tp.Topic, tp.Partition, offset)
+	if err != nil {
+		if h.log != nil {
+			h.log.Errorf("consume partition %s/%d: %v", tp.Topic, tp.Partition, err)
+		}
+		return offset
+	}
+	go func() {
+		<-ctx.Done()
+		pc.AsyncClose()
+	}()
+	go func() {
+		for err := range pc.Errors() {
+			h.countError()
+			if h.log != nil {
+				h.log.Errorf("consume partition %s/%d: %v", tp.Topic, tp.Partition, err)
+			}
+		}
+	}()
+
+	h.mu.Lock()
+	if h.paused.IsPaused(tp) {
+		pc.Pause()
+	}
+	h.mu.Unlock()
+
+	msgs := pc.Messages()
+	if h.batchChan != nil {
+		h.collectBatches(ctx, msgs, func(msg *sarama.ConsumerMessage) Message {
+			h.observe(msg, pc.HighWaterMarkOffset())
+			return Message{ConsumerMessage: msg}
+		}, func(last *sarama.ConsumerMessage) {
+			offset = last.Offset + 1
+		})
+	}
+	for msg := range msgs {
+		if ctx.Err() != nil {
+			// NOTE: drain until the partition consumer is closed
+			continue
+		}
+		h.observe(msg, pc.HighWaterMarkOffset())
+		select {
+		case h.msgChan <- Message{ConsumerMessage: msg}:
+			offset = msg.Offset + 1
+		case <-ctx.Done():
+		}
+	}
+	return offset
+}
+
+// lookupPartitions returns the configured partitions of every topic,
+// or all of them if none is configured.
+func (h *Handler) lookupPartitions() ([]sk.TopicPartition, error) {
+	var tps []sk.TopicPartition
+	for _, topic := range h.topics {
+		partitions := h.partitions
+		if len(partitions) == 0 {
+			var err error
+			partitions, err = h.client.Partitions(topic)
+			if err != nil {
+				return nil, fmt.Errorf("lookup partitions of %s: %w", topic, err)
+			}
+		}
+		for _, p := range partitions {
+			tps = append(tps, sk.TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+	return tps, nil
+}
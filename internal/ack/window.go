@@ -0,0 +1,126 @@
+package ack
+
+import (
+	"sync"
+)
+
+type state uint8
+
+const (
+	inflight state = iota
+	acked
+	nacked
+)
+
+// Window tracks the in-flight offsets of a single partition, it reports
+// a new commit position only once every earlier offset has been acked.
+type Window struct {
+	mu      sync.Mutex
+	pending []int64
+	state   map[int64]state
+}
+
+// Track registers a dispatched offset, commit is called with the next
+// offset to consume whenever the commit position moves forward.
+func (w *Window) Track(offset int64, commit func(next int64)) *Handle {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if n := len(w.pending); n > 0 && offset <= w.pending[n-1] {
+		// NOTE: partition was rewound (rebalance or seek),
+		// offsets still in flight will be delivered again.
+		w.pending = w.pending[:0]
+		w.state = nil
+	}
+	if w.state == nil {
+		w.state = make(map[int64]state)
+	}
+	w.pending = append(w.pending, offset)
+	w.state[offset] = inflight
+
+	return &Handle{
+		window: w,
+		offset: offset,
+		commit: commit,
+	}
+}
+
+func (w *Window) ack(offset int64, commit func(int64)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if st, ok := w.state[offset]; !ok || st != inflight {
+		return
+	}
+	w.state[offset] = acked
+
+	next := int64(-1)
+	for len(w.pending) > 0 && w.state[w.pending[0]] == acked {
+		next = w.pending[0] + 1
+		delete(w.state, w.pending[0])
+		w.pending = w.pending[1:]
+	}
+	if next >= 0 && commit != nil {
+		// NOTE: commit under lock, so commits of one partition never go backwards.
+		commit(next)
+	}
+}
+
+func (w *Window) nack(offset int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if st, ok := w.state[offset]; ok && st == inflight {
+		w.state[offset] = nacked
+	}
+}
+
+// Handle acknowledges a single tracked offset, only the first call counts.
+type Handle struct {
+	once   sync.Once
+	window *Window
+	offset int64
+	commit func(int64)
+}
+
+func (h *Handle) Ack() {
+	h.once.Do(func() {
+		h.window.ack(h.offset, h.commit)
+	})
+}
+
+// Nack leaves the offset uncommitted, so the commit position of its
+// partition stops before it and it will be consumed again after a
+// rebalance or restart.
+func (h *Handle) Nack() {
+	h.once.Do(func() {
+		h.window.nack(h.offset)
+	})
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// Windows holds one Window per topic partition.
+type Windows struct {
+	mu sync.Mutex
+	m  map[partitionKey]*Window
+}
+
+func (ws *Windows) Get(topic string, partition int32) *Window {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.m == nil {
+		ws.m = make(map[partitionKey]*Window)
+	}
+	key := partitionKey{topic: topic, partition: partition}
+	w, ok := ws.m[key]
+	if !ok {
+		w = new(Window)
+		ws.m[key] = w
+	}
+	return w
+}
@@ -0,0 +1,89 @@
+package batch
+
+import (
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+const (
+	defaultMaxMessages = 100
+	defaultMaxWait     = time.Second
+)
+
+type Limits struct {
+	MaxMessages int
+	MaxBytes    int
+	MaxWait     time.Duration
+}
+
+func NewLimits(c *sk.BatchConfig) Limits {
+	l := Limits{
+		MaxMessages: defaultMaxMessages,
+		MaxWait:     defaultMaxWait,
+	}
+	if c == nil {
+		return l
+	}
+	if v := c.MaxMessages; v > 0 {
+		l.MaxMessages = v
+	}
+	if v := c.MaxBytes; v > 0 {
+		l.MaxBytes = v
+	}
+	if v := c.MaxWait; v > 0 {
+		l.MaxWait = v
+	}
+	return l
+}
+
+// Batch implements sk.Batch.
+type Batch struct {
+	topic     string
+	partition int32
+	messages  []sk.Message
+	bytes     int
+}
+
+func New(topic string, partition int32) *Batch {
+	return &Batch{
+		topic:     topic,
+		partition: partition,
+	}
+}
+
+func (b *Batch) Add(msg sk.Message) {
+	b.messages = append(b.messages, msg)
+	b.bytes += len(msg.Key()) + len(msg.Value())
+}
+
+func (b *Batch) Full(l Limits) bool {
+	if len(b.messages) >= l.MaxMessages {
+		return true
+	}
+	return l.MaxBytes > 0 && b.bytes >= l.MaxBytes
+}
+
+func (b *Batch) Topic() string {
+	return b.topic
+}
+
+func (b *Batch) Partition() int32 {
+	return b.partition
+}
+
+func (b *Batch) Messages() []sk.Message {
+	return b.messages
+}
+
+func (b *Batch) Ack() {
+	for _, msg := range b.messages {
+		msg.Ack()
+	}
+}
+
+func (b *Batch) Nack() {
+	for _, msg := range b.messages {
+		msg.Nack()
+	}
+}
@@ -0,0 +1,44 @@
+// Package gometrics reads the go-metrics registry of sarama.
+package gometrics
+
+import (
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Meters returns deltas of meter counts between calls.
+type Meters struct {
+	Registry metrics.Registry
+
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+// Delta returns the count of meter name since the last call,
+// or 0 if it is not registered yet.
+func (m *Meters) Delta(name string) int64 {
+	meter, ok := m.Registry.Get(name).(metrics.Meter)
+	if !ok {
+		return 0
+	}
+	count := meter.Count()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.last == nil {
+		m.last = make(map[string]int64)
+	}
+	delta := count - m.last[name]
+	m.last[name] = count
+	return delta
+}
+
+// Mean returns the mean of histogram name, or 0 if it is not registered yet.
+func Mean(r metrics.Registry, name string) float64 {
+	h, ok := r.Get(name).(metrics.Histogram)
+	if !ok {
+		return 0
+	}
+	return h.Snapshot().Mean()
+}
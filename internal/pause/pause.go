@@ -0,0 +1,85 @@
+package pause
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Set holds paused partitions, they stay paused across rebalances
+// until resumed.
+type Set struct {
+	mu     sync.Mutex
+	paused map[sk.TopicPartition]chan struct{}
+}
+
+func (s *Set) Pause(tps ...sk.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused == nil {
+		s.paused = make(map[sk.TopicPartition]chan struct{})
+	}
+	for _, tp := range tps {
+		if _, ok := s.paused[tp]; !ok {
+			s.paused[tp] = make(chan struct{})
+		}
+	}
+}
+
+func (s *Set) Resume(tps ...sk.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tp := range tps {
+		if ch, ok := s.paused[tp]; ok {
+			close(ch)
+			delete(s.paused, tp)
+		}
+	}
+}
+
+func (s *Set) IsPaused(tp sk.TopicPartition) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.paused[tp]
+	return ok
+}
+
+// List returns paused partitions in order.
+func (s *Set) List() []sk.TopicPartition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tps := make([]sk.TopicPartition, 0, len(s.paused))
+	for tp := range s.paused {
+		tps = append(tps, tp)
+	}
+	sort.Slice(tps, func(i, j int) bool {
+		if tps[i].Topic != tps[j].Topic {
+			return tps[i].Topic < tps[j].Topic
+		}
+		return tps[i].Partition < tps[j].Partition
+	})
+	return tps
+}
+
+// Wait blocks while tp is paused.
+func (s *Set) Wait(ctx context.Context, tp sk.TopicPartition) error {
+	s.mu.Lock()
+	ch, ok := s.paused[tp]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,101 @@
+package seek
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Position is where a partition continues from,
+// Time is resolved by the backend if it is set.
+type Position struct {
+	Offset int64
+	Time   time.Time
+}
+
+// Resets holds the positions partitions should be moved to. The start
+// position of config applies once per partition, the first time it is
+// assigned, seeks apply the next time they are taken.
+type Resets struct {
+	// Initial is FirstOffset or LastOffset, used by partitions without
+	// committed offset, zero leaves the backend default.
+	Initial int64
+
+	mu      sync.Mutex
+	start   *Position
+	pending map[sk.TopicPartition]Position
+	seen    map[sk.TopicPartition]bool
+}
+
+// New parses start_offset, start_offsets and start_time of c.
+func New(c sk.ConsumerConfig) (*Resets, error) {
+	r := &Resets{
+		pending: make(map[sk.TopicPartition]Position),
+		seen:    make(map[sk.TopicPartition]bool),
+	}
+
+	switch v := strings.ToLower(c.StartOffset); v {
+	case "":
+	case "first":
+		r.Initial = sk.FirstOffset
+	case "last":
+		r.Initial = sk.LastOffset
+	default:
+		offset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("start_offset %s not support", c.StartOffset)
+		}
+		r.start = &Position{Offset: offset}
+	}
+	if !c.StartTime.IsZero() {
+		if r.start != nil {
+			return nil, errors.New("start_offset and start_time are exclusive")
+		}
+		r.start = &Position{Time: c.StartTime}
+	}
+	for _, o := range c.StartOffsets {
+		if err := Validate(o); err != nil {
+			return nil, err
+		}
+		r.pending[sk.TopicPartition{Topic: o.Topic, Partition: o.Partition}] = Position{Offset: o.Offset}
+	}
+	return r, nil
+}
+
+// Take returns the position tp should be moved to, if any.
+func (r *Resets) Take(tp sk.TopicPartition) (Position, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	first := !r.seen[tp]
+	r.seen[tp] = true
+	if p, ok := r.pending[tp]; ok {
+		delete(r.pending, tp)
+		return p, true
+	}
+	if first && r.start != nil {
+		return *r.start, true
+	}
+	return Position{}, false
+}
+
+// Seek makes p the next position of tp.
+func (r *Resets) Seek(tp sk.TopicPartition, p Position) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[tp] = p
+}
+
+// Validate checks an offset passed to Seek.
+func Validate(o sk.PartitionOffset) error {
+	if o.Offset < 0 && o.Offset != sk.FirstOffset && o.Offset != sk.LastOffset {
+		return fmt.Errorf("offset %d of %s/%d not support", o.Offset, o.Topic, o.Partition)
+	}
+	return nil
+}
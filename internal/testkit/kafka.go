@@ -0,0 +1,70 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Brokers returns addresses of the kafka integration tests run against,
+// they are skipped unless KAFKA_BROKERS is set.
+func Brokers(t testing.TB) []string {
+	t.Helper()
+	v := os.Getenv("KAFKA_BROKERS")
+	if v == "" {
+		t.Skip("KAFKA_BROKERS is not set")
+	}
+	return strings.Split(v, ",")
+}
+
+// CreateTopic creates a topic named after the test.
+func CreateTopic(t testing.TB, addrs []string, partitions int) string {
+	t.Helper()
+	topic := fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+
+	conn, err := kafka.Dial("tcp", addrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	controller, err := conn.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if err := cc.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: partitions, ReplicationFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+	return topic
+}
+
+// Produce writes values to partition of topic.
+func Produce(t testing.TB, addrs []string, topic string, partition int, values ...string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	conn, err := kafka.DialLeader(ctx, "tcp", addrs[0], topic, partition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	msgs := make([]kafka.Message, 0, len(values))
+	for _, v := range values {
+		msgs = append(msgs, kafka.Message{Value: []byte(v)})
+	}
+	if _, err := conn.WriteMessages(msgs...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package testkit holds helpers of consumer tests shared by backends.
+package testkit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Timeout is how long helpers wait before failing a test.
+const Timeout = 30 * time.Second
+
+// Receive returns the next n messages of c.
+func Receive(t testing.TB, c sk.Consumer, n int) []sk.Message {
+	t.Helper()
+	msgs := make([]sk.Message, 0, n)
+	for len(msgs) < n {
+		select {
+		case msg, ok := <-c.Receive():
+			if !ok {
+				t.Fatalf("consumer closed after %d of %d messages", len(msgs), n)
+			}
+			msgs = append(msgs, msg)
+		case <-time.After(Timeout):
+			t.Fatalf("got %d of %d messages", len(msgs), n)
+		}
+	}
+	return msgs
+}
+
+// ReceiveNone fails if c delivers a message within d.
+func ReceiveNone(t testing.TB, c sk.Consumer, d time.Duration) {
+	t.Helper()
+	select {
+	case msg := <-c.Receive():
+		t.Fatalf("got unexpected message %s of %s/%d at %d", msg.Value(), msg.Topic(), msg.Partition(), msg.Offset())
+	case <-time.After(d):
+	}
+}
+
+// Values returns the set of values of msgs.
+func Values(msgs []sk.Message) map[string]bool {
+	vs := make(map[string]bool, len(msgs))
+	for _, msg := range msgs {
+		vs[string(msg.Value())] = true
+	}
+	return vs
+}
+
+// WaitFor polls cond until it is true.
+func WaitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(Timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Assignments records partitions of a consumer by its rebalance callbacks.
+type Assignments struct {
+	mu  sync.Mutex
+	tps []sk.TopicPartition
+}
+
+func (a *Assignments) OnAssigned(tps []sk.TopicPartition) {
+	a.mu.Lock()
+	a.tps = tps
+	a.mu.Unlock()
+}
+
+func (a *Assignments) OnRevoked([]sk.TopicPartition) {
+	a.mu.Lock()
+	a.tps = nil
+	a.mu.Unlock()
+}
+
+func (a *Assignments) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.tps)
+}
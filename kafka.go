@@ -1,20 +1,131 @@
 package kafka
 
+import (
+	"context"
+	"time"
+)
+
 type Consumer interface {
 	Run() error
 	Stop()
 	Receive() <-chan Message
+	Pauser
+}
+
+// BatchConsumer delivers messages in batches of a single partition,
+// consumers run in batch mode when batch is set in config.
+type BatchConsumer interface {
+	Run() error
+	Stop()
+	ReceiveBatch() <-chan Batch
+	Pauser
+}
+
+// Pauser stops fetching partitions without leaving the group, partitions
+// stay paused across rebalances until resumed. Messages fetched before
+// the pause are still delivered.
+type Pauser interface {
+	Pause(partitions ...TopicPartition)
+	Resume(partitions ...TopicPartition)
+	Paused() []TopicPartition
+}
+
+// Relative offsets of PartitionOffset.
+const (
+	LastOffset  int64 = -1
+	FirstOffset int64 = -2
+)
+
+// Seeker is implemented by consumers which can move their position at
+// runtime, messages already dispatched are not affected.
+type Seeker interface {
+	// Seek moves partitions to the given offsets, partitions not assigned
+	// to the consumer are moved once they are.
+	Seek(offsets ...PartitionOffset) error
+	// SeekTime moves every assigned partition to the first offset
+	// whose timestamp is not before t.
+	SeekTime(t time.Time) error
 }
 
 type Producer interface {
 	Stop()
 	Send(topic string, value []byte) error
 	SendWithKey(topic string, key, value []byte) error
+	SendContext(ctx context.Context, topic string, value []byte) error
+	SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error
+	SendMessage(ctx context.Context, msg *ProducerMessage) error
+}
+
+// TxnProducer produces messages in transactions, consumers with
+// read_committed isolation only see messages of committed ones.
+type TxnProducer interface {
+	Producer
+	BeginTxn() error
+	CommitTxn() error
+	AbortTxn() error
+	// SendOffsetsToTxn commits offsets of consumer group groupID as part of
+	// the transaction, offsets are the next ones to consume.
+	SendOffsetsToTxn(offsets []PartitionOffset, groupID string) error
+}
+
+// ProducerMessage is a backend-neutral message to produce.
+type ProducerMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []Header
+	// Partition pins the message to a partition,
+	// leave it nil to let the balancer choose one.
+	Partition *int32
+	// Timestamp will be set by the client if it is zero.
+	Timestamp time.Time
 }
 
 type Message interface {
+	Key() []byte
 	Value() []byte
+	Headers() []Header
 	Topic() string
 	Partition() int32
 	Offset() int64
+	Timestamp() time.Time
+
+	// Ack marks the message as processed, it only takes effect with
+	// manual_ack enabled, where offsets of a partition are committed
+	// once all earlier messages are acked.
+	Ack()
+	// Nack keeps the message uncommitted, it will be consumed again
+	// after a rebalance or restart.
+	Nack()
+}
+
+// Batch holds messages of one partition in offset order,
+// Ack and Nack apply to all of them.
+type Batch interface {
+	Topic() string
+	Partition() int32
+	Messages() []Message
+	Ack()
+	Nack()
+}
+
+// TopicPartition identifies a partition of a topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// Header is a backend-neutral kafka record header.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// DeliveryReport is the result of producing a single message,
+// Offset is -1 if Err is set.
+type DeliveryReport struct {
+	Message   *ProducerMessage
+	Partition int32
+	Offset    int64
+	Err       error
 }
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// PartitionLag is how far a consumer group is behind on a partition.
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	// Committed is -1 if the group has not committed the partition
+	Committed int64 `json:"committed"`
+	// End is the log-end offset, aka high water mark
+	End int64 `json:"end"`
+	// Lag is -1 if Committed is unknown
+	Lag int64 `json:"lag"`
+}
+
+// NewPartitionLag computes the lag of committed to end.
+func NewPartitionLag(topic string, partition int32, committed, end int64) PartitionLag {
+	l := PartitionLag{
+		Topic:     topic,
+		Partition: partition,
+		Committed: committed,
+		End:       end,
+		Lag:       -1,
+	}
+	if committed >= 0 {
+		l.Lag = end - committed
+		// NOTE: committed may be ahead of a truncated log
+		if l.Lag < 0 {
+			l.Lag = 0
+		}
+	}
+	return l
+}
+
+// SortLags sorts lags by topic and partition.
+func SortLags(lags []PartitionLag) {
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Topic != lags[j].Topic {
+			return lags[i].Topic < lags[j].Topic
+		}
+		return lags[i].Partition < lags[j].Partition
+	})
+}
+
+// LagReader reads lag of consumer groups.
+type LagReader interface {
+	// Lag returns lags of groupID on every partition of topics, sorted by
+	// topic and partition, topics committed by the group if topics is empty.
+	Lag(ctx context.Context, groupID string, topics ...string) ([]PartitionLag, error)
+	Close() error
+}
+
+type LagFactory func(c ConsumerConfig) (LagReader, error)
+
+var lags = make(map[string]LagFactory)
+
+// RegisterLag makes a lag reader backend available by name,
+// it panics if the name is registered twice.
+func RegisterLag(name string, f LagFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if f == nil {
+		panic("kafka: register lag factory is nil")
+	}
+	if _, dup := lags[name]; dup {
+		panic("kafka: register lag twice for backend " + name)
+	}
+	lags[name] = f
+}
+
+// NewLagReader creates a lag reader of the backend selected by config,
+// only addresses, version, tls and sasl of config are used.
+func NewLagReader(c ConsumerConfig) (LagReader, error) {
+	name := backendName(c.Backend)
+	backendsMu.RLock()
+	f, ok := lags[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown lag backend %q (forgotten import?)", name)
+	}
+	return f(c)
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Backend is the name of this package in the backend registry,
+// brokers are looked up by the first address of config.
+const Backend = "memory"
+
+func init() {
+	sk.RegisterConsumer(Backend, func(c sk.ConsumerConfig, o sk.Options) (sk.Consumer, error) {
+		var options []ConsumerOption
+		if o.OnAssigned != nil {
+			options = append(options, WithOnAssigned(o.OnAssigned))
+		}
+		if o.OnRevoked != nil {
+			options = append(options, WithOnRevoked(o.OnRevoked))
+		}
+
+		h, err := NewConsumer(Lookup(brokerName(c.Addresses)), c, options...)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	})
+	sk.RegisterProducer(Backend, func(c sk.ProducerConfig, o sk.Options) (sk.Producer, error) {
+		var options []ProducerOption
+		if o.DeliveryReport != nil {
+			options = append(options, WithDeliveryReport(o.DeliveryReport))
+		}
+
+		p, err := NewProducer(Lookup(brokerName(c.Addresses)), c, options...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	sk.RegisterLag(Backend, func(c sk.ConsumerConfig) (sk.LagReader, error) {
+		return lagReader{broker: Lookup(brokerName(c.Addresses))}, nil
+	})
+}
+
+type lagReader struct {
+	broker *Broker
+}
+
+func (r lagReader) Lag(_ context.Context, groupID string, topics ...string) ([]sk.PartitionLag, error) {
+	return r.broker.Lag(groupID, topics...), nil
+}
+
+func (r lagReader) Close() error {
+	return nil
+}
+
+func brokerName(addresses []string) string {
+	if len(addresses) == 0 {
+		return ""
+	}
+	return addresses[0]
+}
@@ -0,0 +1,45 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+func TestBatch(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{})
+	produce(t, p, "test", 0, "0", "1", "2", "3", "4", "5", "6")
+
+	c := newConsumer(t, b, sk.ConsumerConfig{
+		Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true,
+		Batch: &sk.BatchConfig{MaxMessages: 3},
+	})
+	var sizes []int
+	for n := 0; n < 7; {
+		select {
+		case batch := <-c.ReceiveBatch():
+			msgs := batch.Messages()
+			if v := msgs[0].Offset(); v != int64(n) {
+				t.Fatalf("got batch from %d, want %d", v, n)
+			}
+			sizes = append(sizes, len(msgs))
+			n += len(msgs)
+			batch.Ack()
+		case <-time.After(testkit.Timeout):
+			t.Fatal("no batch received")
+		}
+	}
+	for _, size := range sizes {
+		if size > 3 {
+			t.Fatalf("got batches of %v, want at most 3 messages", sizes)
+		}
+	}
+	testkit.WaitFor(t, "batches committed", func() bool {
+		offset, _ := b.Committed("group", "test", 0)
+		return offset == 7
+	})
+}
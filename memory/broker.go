@@ -0,0 +1,423 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+type record struct {
+	key       []byte
+	value     []byte
+	headers   []sk.Header
+	timestamp time.Time
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type group struct {
+	generation  int
+	members     []*Consumer
+	assignments map[*Consumer][]topicPartition
+	committed   map[topicPartition]int64
+}
+
+// Broker is an in-memory kafka cluster, it is safe for concurrent use.
+type Broker struct {
+	mu                sync.Mutex
+	defaultPartitions int32
+	topics            map[string][][]record
+	groups            map[string]*group
+	// closed and replaced whenever records or assignments change
+	notify chan struct{}
+}
+
+// NewBroker creates a broker which creates missing topics
+// with defaultPartitions partitions.
+func NewBroker(defaultPartitions int32) *Broker {
+	if defaultPartitions <= 0 {
+		defaultPartitions = 1
+	}
+	return &Broker{
+		defaultPartitions: defaultPartitions,
+		topics:            make(map[string][][]record),
+		groups:            make(map[string]*group),
+		notify:            make(chan struct{}),
+	}
+}
+
+var (
+	brokersMu sync.Mutex
+	brokers   = make(map[string]*Broker)
+)
+
+// Lookup returns the broker registered by name, it is created on first use.
+// The backend registry finds brokers by the first address of config.
+func Lookup(name string) *Broker {
+	brokersMu.Lock()
+	defer brokersMu.Unlock()
+
+	b, ok := brokers[name]
+	if !ok {
+		b = NewBroker(1)
+		brokers[name] = b
+	}
+	return b
+}
+
+// CreateTopic creates a topic with the given number of partitions.
+func (b *Broker) CreateTopic(name string, partitions int32) error {
+	if partitions <= 0 {
+		return fmt.Errorf("invalid partitions %d", partitions)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[name]; ok {
+		return fmt.Errorf("topic %s already exists", name)
+	}
+	b.topics[name] = make([][]record, partitions)
+	b.rebalanceAllLocked()
+	return nil
+}
+
+// Partitions returns the number of partitions of topic, or 0 if it does not exist.
+func (b *Broker) Partitions(topic string) int32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int32(len(b.topics[topic]))
+}
+
+// HighWaterMark returns the offset of the next record of a partition.
+func (b *Broker) HighWaterMark(topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	parts := b.topics[topic]
+	if partition < 0 || int(partition) >= len(parts) {
+		return 0
+	}
+	return int64(len(parts[partition]))
+}
+
+// Committed returns the committed offset of a group on a partition.
+func (b *Broker) Committed(groupID, topic string, partition int32) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[groupID]
+	if !ok {
+		return 0, false
+	}
+	offset, ok := g.committed[topicPartition{topic: topic, partition: partition}]
+	return offset, ok
+}
+
+// Lag returns lags of a group on every partition of topics,
+// topics committed by the group if topics is empty.
+func (b *Broker) Lag(groupID string, topics ...string) []sk.PartitionLag {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.groups[groupID]
+	if len(topics) == 0 && g != nil {
+		set := make(map[string]struct{})
+		for tp := range g.committed {
+			set[tp.topic] = struct{}{}
+		}
+		for topic := range set {
+			topics = append(topics, topic)
+		}
+	}
+
+	var lags []sk.PartitionLag
+	for _, topic := range topics {
+		for i, records := range b.topics[topic] {
+			committed := int64(-1)
+			if g != nil {
+				if offset, ok := g.committed[topicPartition{topic: topic, partition: int32(i)}]; ok {
+					committed = offset
+				}
+			}
+			lags = append(lags, sk.NewPartitionLag(topic, int32(i), committed, int64(len(records))))
+		}
+	}
+	sk.SortLags(lags)
+	return lags
+}
+
+func (b *Broker) partitionsLocked(topic string) [][]record {
+	parts, ok := b.topics[topic]
+	if !ok {
+		parts = make([][]record, b.defaultPartitions)
+		b.topics[topic] = parts
+	}
+	return parts
+}
+
+func (b *Broker) broadcastLocked() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *Broker) produce(msg *sk.ProducerMessage, choose func(numPartitions int32) (int32, error)) (int32, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partition, err := b.placeLocked(msg, choose)
+	if err != nil {
+		return -1, -1, err
+	}
+	offset, created := b.appendLocked(msg, partition)
+	if created {
+		b.rebalanceAllLocked()
+	}
+	b.broadcastLocked()
+	return partition, offset, nil
+}
+
+// produceTxn writes msgs and commits offsets of groups at once, nothing
+// is written if any of msgs is invalid. It returns partitions and
+// offsets of msgs.
+func (b *Broker) produceTxn(msgs []*sk.ProducerMessage, offsets map[string][]sk.PartitionOffset, choose func(msg *sk.ProducerMessage, numPartitions int32) (int32, error)) ([]int32, []int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := make([]int32, len(msgs))
+	for i, msg := range msgs {
+		msg := msg
+		partition, err := b.placeLocked(msg, func(numPartitions int32) (int32, error) {
+			return choose(msg, numPartitions)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		partitions[i] = partition
+	}
+
+	written := make([]int64, len(msgs))
+	created := false
+	for i, msg := range msgs {
+		offset, c := b.appendLocked(msg, partitions[i])
+		written[i] = offset
+		created = created || c
+	}
+	for groupID, os := range offsets {
+		for _, o := range os {
+			b.commitLocked(groupID, nil, topicPartition{topic: o.Topic, partition: o.Partition}, o.Offset)
+		}
+	}
+	if created {
+		b.rebalanceAllLocked()
+	}
+	b.broadcastLocked()
+	return partitions, written, nil
+}
+
+// placeLocked returns the partition of msg, a missing topic is not created
+// but counted with the default partitions.
+func (b *Broker) placeLocked(msg *sk.ProducerMessage, choose func(numPartitions int32) (int32, error)) (int32, error) {
+	if msg.Topic == "" {
+		return -1, fmt.Errorf("topic is empty")
+	}
+	numPartitions := b.defaultPartitions
+	if parts, ok := b.topics[msg.Topic]; ok {
+		numPartitions = int32(len(parts))
+	}
+
+	var partition int32
+	if v := msg.Partition; v != nil {
+		partition = *v
+	} else {
+		var err error
+		if partition, err = choose(numPartitions); err != nil {
+			return -1, err
+		}
+	}
+	if partition < 0 || partition >= numPartitions {
+		return -1, fmt.Errorf("partition %d of topic %s out of range", partition, msg.Topic)
+	}
+	return partition, nil
+}
+
+// appendLocked writes msg to a partition checked by placeLocked,
+// created tells if the topic of msg is created by it.
+func (b *Broker) appendLocked(msg *sk.ProducerMessage, partition int32) (offset int64, created bool) {
+	_, ok := b.topics[msg.Topic]
+	parts := b.partitionsLocked(msg.Topic)
+
+	ts := msg.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	offset = int64(len(parts[partition]))
+	parts[partition] = append(parts[partition], record{
+		key:       clone(msg.Key),
+		value:     clone(msg.Value),
+		headers:   cloneHeaders(msg.Headers),
+		timestamp: ts,
+	})
+	return offset, !ok
+}
+
+func (b *Broker) join(groupID string, c *Consumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, t := range c.topics {
+		b.partitionsLocked(t)
+	}
+
+	g := b.groupLocked(groupID)
+	g.members = append(g.members, c)
+	b.rebalanceLocked(g)
+}
+
+// groupLocked returns the group of groupID, it is created if not yet.
+func (b *Broker) groupLocked(groupID string) *group {
+	g, ok := b.groups[groupID]
+	if !ok {
+		g = &group{
+			committed: make(map[topicPartition]int64),
+		}
+		b.groups[groupID] = g
+	}
+	return g
+}
+
+// standalone returns a group of c alone, it reads partitions of every topic
+// of c, all of them if partitions is empty.
+func (b *Broker) standalone(c *Consumer, partitions []int32) *group {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := &group{
+		assignments: make(map[*Consumer][]topicPartition),
+		committed:   make(map[topicPartition]int64),
+	}
+	for _, t := range c.topics {
+		n := int32(len(b.partitionsLocked(t)))
+		if len(partitions) == 0 {
+			for p := int32(0); p < n; p++ {
+				g.assignments[c] = append(g.assignments[c], topicPartition{topic: t, partition: p})
+			}
+			continue
+		}
+		for _, p := range partitions {
+			if p < n {
+				g.assignments[c] = append(g.assignments[c], topicPartition{topic: t, partition: p})
+			}
+		}
+	}
+	return g
+}
+
+func (b *Broker) leave(groupID string, c *Consumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[groupID]
+	if !ok {
+		return
+	}
+	for i, m := range g.members {
+		if m == c {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+	b.rebalanceLocked(g)
+}
+
+func (b *Broker) rebalanceAllLocked() {
+	for _, g := range b.groups {
+		b.rebalanceLocked(g)
+	}
+}
+
+// rebalanceLocked starts a new generation, partitions of every topic are
+// spread round-robin over the members subscribing to it.
+func (b *Broker) rebalanceLocked(g *group) {
+	g.generation++
+	g.assignments = make(map[*Consumer][]topicPartition, len(g.members))
+
+	subscribers := make(map[string][]*Consumer)
+	for _, m := range g.members {
+		for _, t := range m.topics {
+			subscribers[t] = append(subscribers[t], m)
+		}
+	}
+	topics := make([]string, 0, len(subscribers))
+	for t := range subscribers {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	for _, t := range topics {
+		members := subscribers[t]
+		for p := range b.topics[t] {
+			m := members[p%len(members)]
+			g.assignments[m] = append(g.assignments[m], topicPartition{
+				topic:     t,
+				partition: int32(p),
+			})
+		}
+	}
+	b.broadcastLocked()
+}
+
+// commit moves the committed offset of tp forward, commits of a member
+// are dropped once tp is revoked from it.
+func (b *Broker) commit(groupID string, member *Consumer, tp topicPartition, next int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.commitLocked(groupID, member, tp, next)
+}
+
+// commitLocked commits for a transaction if member is nil, the group
+// is created if no consumer has joined it yet, as kafka does.
+func (b *Broker) commitLocked(groupID string, member *Consumer, tp topicPartition, next int64) {
+	g := b.groupLocked(groupID)
+	if member != nil && !hasPartition(g.assignments[member], tp) {
+		return
+	}
+	if cur, ok := g.committed[tp]; !ok || next > cur {
+		g.committed[tp] = next
+	}
+}
+
+func hasPartition(tps []topicPartition, tp topicPartition) bool {
+	for _, v := range tps {
+		if v == tp {
+			return true
+		}
+	}
+	return false
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func cloneHeaders(headers []sk.Header) []sk.Header {
+	if headers == nil {
+		return nil
+	}
+	out := make([]sk.Header, len(headers))
+	for i, h := range headers {
+		out[i] = sk.Header{Key: h.Key, Value: clone(h.Value)}
+	}
+	return out
+}
@@ -0,0 +1,434 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/ack"
+	"github.com/sko00o/kafka/internal/batch"
+	"github.com/sko00o/kafka/internal/pause"
+	"github.com/sko00o/kafka/internal/seek"
+)
+
+type ConsumerOption func(*Consumer) error
+
+// WithOnAssigned sets the callback of partitions assigned by a rebalance.
+func WithOnAssigned(fn func([]sk.TopicPartition)) ConsumerOption {
+	return func(h *Consumer) error {
+		h.onAssigned = fn
+		return nil
+	}
+}
+
+// WithOnRevoked sets the callback of partitions revoked by a rebalance or stop.
+func WithOnRevoked(fn func([]sk.TopicPartition)) ConsumerOption {
+	return func(h *Consumer) error {
+		h.onRevoked = fn
+		return nil
+	}
+}
+
+// Consumer is a group member of a Broker, or reads partitions on its own
+// without group_id, it implements sk.Consumer.
+type Consumer struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	broker  *Broker
+	groupID string
+	// group-less mode if groupID is empty
+	partitions []int32
+	standalone *group
+	topics     []string
+	resets     *seek.Resets
+	paused     pause.Set
+	manualAck  bool
+	msgChan    chan sk.Message
+	windows    ack.Windows
+
+	onAssigned func([]sk.TopicPartition)
+	onRevoked  func([]sk.TopicPartition)
+
+	batchChan chan sk.Batch
+	limits    batch.Limits
+}
+
+func NewConsumer(b *Broker, c sk.ConsumerConfig, options ...ConsumerOption) (*Consumer, error) {
+	if len(c.Topics) == 0 {
+		return nil, errors.New("topics is empty")
+	}
+	switch v := c.IsolationLevel; v {
+	case "", "read_uncommitted", "read_committed":
+		// NOTE: messages of a transaction are only written on commit
+	default:
+		return nil, fmt.Errorf("isolation_level %s not support", v)
+	}
+
+	resets, err := seek.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Consumer{
+		ctx:        ctx,
+		cancel:     cancel,
+		broker:     b,
+		groupID:    c.GroupID,
+		partitions: c.Partitions,
+		topics:     c.Topics,
+		resets:     resets,
+		manualAck:  c.ManualAck && c.GroupID != "",
+	}
+	if cnt := int(c.WorkerCnt); cnt > 0 {
+		h.msgChan = make(chan sk.Message, cnt)
+	} else {
+		h.msgChan = make(chan sk.Message, 1)
+	}
+	if c.Batch != nil {
+		h.batchChan = make(chan sk.Batch, cap(h.msgChan))
+		h.limits = batch.NewLimits(c.Batch)
+	}
+
+	for _, option := range options {
+		if err := option(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+func (h *Consumer) Run() error {
+	if h.groupID == "" {
+		h.standalone = h.broker.standalone(h, h.partitions)
+	} else {
+		h.broker.join(h.groupID, h)
+	}
+	go func() {
+		defer close(h.msgChan)
+		if h.batchChan != nil {
+			defer close(h.batchChan)
+		}
+		if h.standalone == nil {
+			defer h.broker.leave(h.groupID, h)
+		}
+
+		h.run()
+	}()
+	return nil
+}
+
+// groupLocked returns the group of h, or its own one in group-less mode.
+func (h *Consumer) groupLocked() *group {
+	if h.standalone != nil {
+		return h.standalone
+	}
+	return h.broker.groups[h.groupID]
+}
+
+// commit does nothing in group-less mode.
+func (h *Consumer) commit(tp topicPartition, next int64) {
+	if h.standalone != nil {
+		return
+	}
+	h.broker.commit(h.groupID, h, tp, next)
+}
+
+func (h *Consumer) Stop() {
+	h.cancel()
+}
+
+func (h *Consumer) Receive() <-chan sk.Message {
+	return h.msgChan
+}
+
+// ReceiveBatch returns nil if batch mode is disabled, batches hold
+// the records available at the time, max_wait is not used.
+func (h *Consumer) ReceiveBatch() <-chan sk.Batch {
+	return h.batchChan
+}
+
+func (h *Consumer) run() {
+	var (
+		generation = -1
+		assigned   []topicPartition
+		positions  map[topicPartition]int64
+		next       int
+	)
+	defer func() {
+		if generation >= 0 && h.onRevoked != nil {
+			h.onRevoked(topicPartitions(assigned))
+		}
+	}()
+
+	for {
+		b := h.broker
+		b.mu.Lock()
+		g := h.groupLocked()
+		var revoked []topicPartition
+		rebalanced := g.generation != generation
+		if rebalanced {
+			if generation >= 0 {
+				revoked = assigned
+			}
+			generation = g.generation
+			assigned = g.assignments[h]
+			positions = make(map[topicPartition]int64, len(assigned))
+			for _, tp := range assigned {
+				positions[tp] = h.startPosition(g, tp)
+			}
+		}
+
+		for _, tp := range assigned {
+			if p, ok := h.resets.Take(sk.TopicPartition{Topic: tp.topic, Partition: tp.partition}); ok {
+				positions[tp] = h.resolveLocked(tp, p)
+			}
+		}
+
+		// NOTE: take partitions in turns, so none of them starves
+		var (
+			tp   topicPartition
+			msgs []Message
+		)
+		for i := 0; i < len(assigned) && len(msgs) == 0; i++ {
+			tp = assigned[(next+i)%len(assigned)]
+			if h.paused.IsPaused(sk.TopicPartition{Topic: tp.topic, Partition: tp.partition}) {
+				continue
+			}
+			records := b.topics[tp.topic][tp.partition]
+			pos := positions[tp]
+			if pos >= int64(len(records)) {
+				continue
+			}
+			next = (next + i + 1) % len(assigned)
+
+			n := int64(1)
+			if h.batchChan != nil {
+				n = h.batchSize(records[pos:])
+			}
+			for offset := pos; offset < pos+n; offset++ {
+				msgs = append(msgs, Message{
+					record:    records[offset],
+					topic:     tp.topic,
+					partition: tp.partition,
+					offset:    offset,
+				})
+			}
+		}
+		notify := b.notify
+		b.mu.Unlock()
+
+		if rebalanced {
+			if revoked != nil && h.onRevoked != nil {
+				h.onRevoked(topicPartitions(revoked))
+			}
+			if h.onAssigned != nil {
+				h.onAssigned(topicPartitions(assigned))
+			}
+		}
+
+		if len(msgs) == 0 {
+			select {
+			case <-notify:
+				continue
+			case <-h.ctx.Done():
+				return
+			}
+		}
+
+		if h.manualAck {
+			window := h.windows.Get(tp.topic, tp.partition)
+			commit := func(next int64) {
+				h.commit(tp, next)
+			}
+			for i := range msgs {
+				msgs[i].acker = window.Track(msgs[i].offset, commit)
+			}
+		}
+
+		if !h.deliver(msgs) {
+			return
+		}
+		last := msgs[len(msgs)-1].offset
+		positions[tp] = last + 1
+		if !h.manualAck {
+			h.commit(tp, last+1)
+		}
+	}
+}
+
+// batchSize returns how many of records fit in one batch.
+func (h *Consumer) batchSize(records []record) int64 {
+	var n, bytes int
+	for n < len(records) && n < h.limits.MaxMessages {
+		if h.limits.MaxBytes > 0 && n > 0 && bytes >= h.limits.MaxBytes {
+			break
+		}
+		bytes += len(records[n].key) + len(records[n].value)
+		n++
+	}
+	return int64(n)
+}
+
+// deliver returns false if the consumer is stopped.
+func (h *Consumer) deliver(msgs []Message) bool {
+	if h.batchChan == nil {
+		select {
+		case h.msgChan <- msgs[0]:
+			return true
+		case <-h.ctx.Done():
+			return false
+		}
+	}
+
+	b := batch.New(msgs[0].topic, msgs[0].partition)
+	for _, msg := range msgs {
+		b.Add(msg)
+	}
+	select {
+	case h.batchChan <- b:
+		return true
+	case <-h.ctx.Done():
+		return false
+	}
+}
+
+func topicPartitions(tps []topicPartition) []sk.TopicPartition {
+	out := make([]sk.TopicPartition, 0, len(tps))
+	for _, tp := range tps {
+		out = append(out, sk.TopicPartition{Topic: tp.topic, Partition: tp.partition})
+	}
+	return out
+}
+
+func (h *Consumer) startPosition(g *group, tp topicPartition) int64 {
+	if offset, ok := g.committed[tp]; ok {
+		return offset
+	}
+	if h.resets.Initial == sk.LastOffset {
+		return int64(len(h.broker.topics[tp.topic][tp.partition]))
+	}
+	return 0
+}
+
+// resolveLocked turns p into an offset of tp.
+func (h *Consumer) resolveLocked(tp topicPartition, p seek.Position) int64 {
+	records := h.broker.topics[tp.topic][tp.partition]
+	switch {
+	case !p.Time.IsZero():
+		for i, r := range records {
+			if !r.timestamp.Before(p.Time) {
+				return int64(i)
+			}
+		}
+		return int64(len(records))
+	case p.Offset == sk.FirstOffset:
+		return 0
+	case p.Offset == sk.LastOffset, p.Offset > int64(len(records)):
+		return int64(len(records))
+	default:
+		return p.Offset
+	}
+}
+
+// Pause implements sk.Pauser.
+func (h *Consumer) Pause(partitions ...sk.TopicPartition) {
+	h.paused.Pause(partitions...)
+}
+
+func (h *Consumer) Resume(partitions ...sk.TopicPartition) {
+	h.paused.Resume(partitions...)
+
+	h.broker.mu.Lock()
+	h.broker.broadcastLocked()
+	h.broker.mu.Unlock()
+}
+
+func (h *Consumer) Paused() []sk.TopicPartition {
+	return h.paused.List()
+}
+
+// Seek implements sk.Seeker.
+func (h *Consumer) Seek(offsets ...sk.PartitionOffset) error {
+	for _, o := range offsets {
+		if err := seek.Validate(o); err != nil {
+			return err
+		}
+	}
+	for _, o := range offsets {
+		h.resets.Seek(sk.TopicPartition{Topic: o.Topic, Partition: o.Partition}, seek.Position{Offset: o.Offset})
+	}
+
+	h.broker.mu.Lock()
+	h.broker.broadcastLocked()
+	h.broker.mu.Unlock()
+	return nil
+}
+
+// SeekTime implements sk.Seeker.
+func (h *Consumer) SeekTime(t time.Time) error {
+	b := h.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g := h.groupLocked(); g != nil {
+		for _, tp := range g.assignments[h] {
+			h.resets.Seek(sk.TopicPartition{Topic: tp.topic, Partition: tp.partition}, seek.Position{Time: t})
+		}
+	}
+	b.broadcastLocked()
+	return nil
+}
+
+type Message struct {
+	record
+	topic     string
+	partition int32
+	offset    int64
+	acker     *ack.Handle
+}
+
+func (m Message) Key() []byte {
+	return m.record.key
+}
+
+func (m Message) Value() []byte {
+	return m.record.value
+}
+
+// Headers returns a copy, so handlers adding or changing headers do not
+// change the record of other groups and redeliveries.
+func (m Message) Headers() []sk.Header {
+	return cloneHeaders(m.record.headers)
+}
+
+func (m Message) Topic() string {
+	return m.topic
+}
+
+func (m Message) Partition() int32 {
+	return m.partition
+}
+
+func (m Message) Offset() int64 {
+	return m.offset
+}
+
+func (m Message) Timestamp() time.Time {
+	return m.record.timestamp
+}
+
+func (m Message) Ack() {
+	if m.acker != nil {
+		m.acker.Ack()
+	}
+}
+
+func (m Message) Nack() {
+	if m.acker != nil {
+		m.acker.Nack()
+	}
+}
@@ -0,0 +1,288 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+func newProducer(t *testing.T, b *memory.Broker, c sk.ProducerConfig, options ...memory.ProducerOption) *memory.Producer {
+	t.Helper()
+	p, err := memory.NewProducer(b, c, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+func newConsumer(t *testing.T, b *memory.Broker, c sk.ConsumerConfig, options ...memory.ConsumerOption) *memory.Consumer {
+	t.Helper()
+	h, err := memory.NewConsumer(b, c, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Stop)
+	return h
+}
+
+func partition(p int32) *int32 {
+	return &p
+}
+
+// recorded returns options recording assignments of a consumer in a.
+func recorded(a *testkit.Assignments) []memory.ConsumerOption {
+	return []memory.ConsumerOption{memory.WithOnAssigned(a.OnAssigned), memory.WithOnRevoked(a.OnRevoked)}
+}
+
+func TestKeyHashPlacement(t *testing.T) {
+	b := memory.NewBroker(8)
+	var reports []sk.DeliveryReport
+	p := newProducer(t, b, sk.ProducerConfig{}, memory.WithDeliveryReport(func(r sk.DeliveryReport) {
+		reports = append(reports, r)
+	}))
+
+	hash, err := sk.NewPartitioner(sk.ProducerConfig{Balancer: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hash("test")
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 20; i++ {
+			if err := p.SendWithKey("test", []byte(fmt.Sprint("key-", i)), []byte("v")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	placed := make(map[string]int32)
+	for _, r := range reports {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		key := string(r.Message.Key)
+		if p, ok := placed[key]; ok && p != r.Partition {
+			t.Fatalf("key %s placed on partitions %d and %d", key, p, r.Partition)
+		}
+		placed[key] = r.Partition
+		if p := want.Partition(r.Message, 8); p != r.Partition {
+			t.Fatalf("key %s placed on partition %d, hash balancer wants %d", key, r.Partition, p)
+		}
+	}
+}
+
+func TestGroupRebalance(t *testing.T) {
+	b := memory.NewBroker(4)
+	if err := b.CreateTopic("test", 4); err != nil {
+		t.Fatal(err)
+	}
+	cfg := sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first"}
+
+	var a1, a2 testkit.Assignments
+	c1 := newConsumer(t, b, cfg, recorded(&a1)...)
+	testkit.WaitFor(t, "c1 assigned", func() bool { return a1.Len() == 4 })
+
+	c2, err := memory.NewConsumer(b, cfg, recorded(&a2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Run(); err != nil {
+		t.Fatal(err)
+	}
+	testkit.WaitFor(t, "partitions spread", func() bool { return a1.Len() == 2 && a2.Len() == 2 })
+
+	p := newProducer(t, b, sk.ProducerConfig{})
+	for i := int32(0); i < 4; i++ {
+		if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "test", Partition: partition(i), Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := make(map[int32]bool)
+	for _, msg := range append(testkit.Receive(t, c1, 2), testkit.Receive(t, c2, 2)...) {
+		got[msg.Partition()] = true
+	}
+	if len(got) != 4 {
+		t.Fatalf("got messages of partitions %v, want all 4", got)
+	}
+
+	c2.Stop()
+	testkit.WaitFor(t, "c1 takes over", func() bool { return a1.Len() == 4 && a2.Len() == 0 })
+	for i := int32(0); i < 4; i++ {
+		testkit.WaitFor(t, "commits", func() bool {
+			offset, ok := b.Committed("group", "test", i)
+			return ok && offset == 1
+		})
+	}
+}
+
+func TestManualAckCommits(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{})
+	for i := 0; i < 4; i++ {
+		if err := p.Send("test", []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := newConsumer(t, b, sk.ConsumerConfig{
+		Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true, WorkerCnt: 4,
+	})
+	msgs := testkit.Receive(t, c, 4)
+
+	committed := func() int64 {
+		offset, ok := b.Committed("group", "test", 0)
+		if !ok {
+			return -1
+		}
+		return offset
+	}
+
+	// NOTE: commits only move past contiguous acks
+	msgs[1].Ack()
+	if v := committed(); v != -1 {
+		t.Fatalf("committed %d before offset 0 is acked", v)
+	}
+	msgs[0].Ack()
+	if v := committed(); v != 2 {
+		t.Fatalf("committed %d, want 2", v)
+	}
+	msgs[2].Nack()
+	msgs[3].Ack()
+	if v := committed(); v != 2 {
+		t.Fatalf("committed %d after a nack, want 2", v)
+	}
+}
+
+func TestRevokedPartitionIsNotCommitted(t *testing.T) {
+	b := memory.NewBroker(2)
+	if err := b.CreateTopic("test", 2); err != nil {
+		t.Fatal(err)
+	}
+	p := newProducer(t, b, sk.ProducerConfig{})
+	if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "test", Partition: partition(1), Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true}
+	c1 := newConsumer(t, b, cfg)
+	msg := testkit.Receive(t, c1, 1)[0]
+
+	// NOTE: partition 1 moves to c2
+	var a2 testkit.Assignments
+	newConsumer(t, b, cfg, recorded(&a2)...)
+	testkit.WaitFor(t, "c2 assigned", func() bool { return a2.Len() == 1 })
+
+	msg.Ack()
+	if offset, ok := b.Committed("group", "test", 1); ok {
+		t.Fatalf("revoked partition committed at %d", offset)
+	}
+}
+
+func TestTxnCopiesMessages(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{TransactionalID: "txn"})
+
+	if err := p.BeginTxn(); err != nil {
+		t.Fatal(err)
+	}
+	msg := &sk.ProducerMessage{Topic: "test", Value: []byte("before")}
+	if err := p.SendMessage(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Topic = "other"
+	copy(msg.Value, "after!")
+	if err := p.CommitTxn(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"test"}, StartOffset: "first"})
+	if v := string(testkit.Receive(t, c, 1)[0].Value()); v != "before" {
+		t.Fatalf("got value %s, want before", v)
+	}
+	if n := b.HighWaterMark("other", 0); n != 0 {
+		t.Fatalf("got %d messages of other", n)
+	}
+}
+
+func TestTxnIsAtomic(t *testing.T) {
+	b := memory.NewBroker(1)
+	newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"in"}, GroupID: "group"})
+	p := newProducer(t, b, sk.ProducerConfig{TransactionalID: "txn"})
+
+	if err := p.BeginTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "out", Value: []byte("ok")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "out", Partition: partition(5), Value: []byte("bad")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendOffsetsToTxn([]sk.PartitionOffset{{Topic: "in", Partition: 0, Offset: 10}}, "group"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CommitTxn(); err == nil {
+		t.Fatal("commit of an invalid message succeeded")
+	}
+
+	if n := b.HighWaterMark("out", 0); n != 0 {
+		t.Fatalf("got %d messages of a failed transaction", n)
+	}
+	if offset, ok := b.Committed("group", "in", 0); ok {
+		t.Fatalf("got offset %d committed by a failed transaction", offset)
+	}
+}
+
+// produce sends values to partition p of topic.
+func produce(t *testing.T, pr *memory.Producer, topic string, p int32, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		if err := pr.SendMessage(context.Background(), &sk.ProducerMessage{Topic: topic, Partition: partition(p), Value: []byte(v)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHeadersAreCopied(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{})
+	if err := p.SendMessage(context.Background(), &sk.ProducerMessage{
+		Topic: "test", Value: []byte("v"), Headers: []sk.Header{{Key: "h", Value: []byte("v")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "g1", StartOffset: "first"})
+	headers := testkit.Receive(t, c, 1)[0].Headers()
+	headers[0].Value[0] = 'x'
+	_ = append(headers[:0], sk.Header{Key: "other"})
+
+	c = newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "g2", StartOffset: "first"})
+	if h := testkit.Receive(t, c, 1)[0].Headers(); len(h) != 1 || h[0].Key != "h" || string(h[0].Value) != "v" {
+		t.Fatalf("got headers %v changed by another group", h)
+	}
+}
+
+func TestTxnCommitsOffsetsOfNewGroup(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{TransactionalID: "txn"})
+
+	if err := p.BeginTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendOffsetsToTxn([]sk.PartitionOffset{{Topic: "in", Partition: 0, Offset: 3}}, "group"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CommitTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if offset, ok := b.Committed("group", "in", 0); !ok || offset != 3 {
+		t.Fatalf("got offset %d, %v of a group not joined yet, want 3", offset, ok)
+	}
+}
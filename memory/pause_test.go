@@ -0,0 +1,36 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+func TestPauseResume(t *testing.T) {
+	b := memory.NewBroker(2)
+	if err := b.CreateTopic("test", 2); err != nil {
+		t.Fatal(err)
+	}
+	p := newProducer(t, b, sk.ProducerConfig{})
+	c := newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first"})
+
+	tp := sk.TopicPartition{Topic: "test", Partition: 0}
+	c.Pause(tp)
+	if paused := c.Paused(); len(paused) != 1 || paused[0] != tp {
+		t.Fatalf("got paused %v", paused)
+	}
+	produce(t, p, "test", 0, "a")
+	produce(t, p, "test", 1, "b")
+	if msg := testkit.Receive(t, c, 1)[0]; msg.Partition() != 1 {
+		t.Fatalf("got a message of paused partition %d", msg.Partition())
+	}
+	testkit.ReceiveNone(t, c, 10*time.Millisecond)
+
+	c.Resume(tp)
+	if msg := testkit.Receive(t, c, 1)[0]; string(msg.Value()) != "a" {
+		t.Fatalf("got %s after resume, want a", msg.Value())
+	}
+}
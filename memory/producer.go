@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	sk "github.com/sko00o/kafka"
+)
+
+var (
+	ErrClosed = errors.New("producer closed")
+	ErrNoTxn  = errors.New("no transaction begun")
+)
+
+type ProducerOption func(*Producer) error
+
+// WithDeliveryReport sets fn to receive the result of every message.
+func WithDeliveryReport(fn func(sk.DeliveryReport)) ProducerOption {
+	return func(p *Producer) error {
+		p.report = fn
+		return nil
+	}
+}
+
+// Producer writes into a Broker, it implements sk.Producer.
+// Messages are placed by the hash balancer if balancer is empty,
+// the same as sarama does.
+//
+// With transactional_id it implements sk.TxnProducer, messages of
+// a transaction are held back until it is committed.
+type Producer struct {
+	broker *Broker
+	report func(sk.DeliveryReport)
+
+	partitioner  sk.PartitionerConstructor
+	partitionsMu sync.Mutex
+	partitioners map[string]sk.Partitioner
+
+	mu     sync.RWMutex
+	closed bool
+
+	transactional bool
+	txnMu         sync.Mutex
+	txn           *txn
+}
+
+type txn struct {
+	// msgs are copies of sent, sent are kept for delivery reports
+	msgs    []*sk.ProducerMessage
+	sent    []*sk.ProducerMessage
+	offsets map[string][]sk.PartitionOffset
+}
+
+func NewProducer(b *Broker, c sk.ProducerConfig, options ...ProducerOption) (*Producer, error) {
+	if c.Balancer == "" {
+		c.Balancer = "hash"
+	}
+	partitioner, err := sk.NewPartitioner(c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Producer{
+		broker:        b,
+		partitioner:   partitioner,
+		partitioners:  make(map[string]sk.Partitioner),
+		transactional: c.TransactionalID != "",
+	}
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Producer) Stop() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+}
+
+func (p *Producer) Send(topic string, value []byte) error {
+	return p.SendWithKeyContext(context.Background(), topic, nil, value)
+}
+
+func (p *Producer) SendWithKey(topic string, key, value []byte) error {
+	return p.SendWithKeyContext(context.Background(), topic, key, value)
+}
+
+func (p *Producer) SendContext(ctx context.Context, topic string, value []byte) error {
+	return p.SendWithKeyContext(ctx, topic, nil, value)
+}
+
+func (p *Producer) SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{
+		Topic: topic,
+		Key:   key,
+		Value: value,
+	})
+}
+
+func (p *Producer) SendMessage(ctx context.Context, msg *sk.ProducerMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if p.transactional {
+		p.txnMu.Lock()
+		defer p.txnMu.Unlock()
+		if p.txn == nil {
+			return ErrNoTxn
+		}
+		// NOTE: the caller may reuse msg before the commit
+		p.txn.msgs = append(p.txn.msgs, copyMessage(msg))
+		p.txn.sent = append(p.txn.sent, msg)
+		return nil
+	}
+
+	return p.produce(msg)
+}
+
+func (p *Producer) produce(msg *sk.ProducerMessage) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	partition, offset, err := p.broker.produce(msg, func(numPartitions int32) (int32, error) {
+		return p.partition(msg, numPartitions), nil
+	})
+	if p.report != nil {
+		p.report(sk.DeliveryReport{
+			Message:   msg,
+			Partition: partition,
+			Offset:    offset,
+			Err:       err,
+		})
+	}
+	return err
+}
+
+func (p *Producer) partition(msg *sk.ProducerMessage, numPartitions int32) int32 {
+	p.partitionsMu.Lock()
+	partitioner, ok := p.partitioners[msg.Topic]
+	if !ok {
+		partitioner = p.partitioner(msg.Topic)
+		p.partitioners[msg.Topic] = partitioner
+	}
+	p.partitionsMu.Unlock()
+	return partitioner.Partition(msg, numPartitions)
+}
+
+// BeginTxn implements sk.TxnProducer.
+func (p *Producer) BeginTxn() error {
+	if !p.transactional {
+		return errors.New("transactional_id is empty")
+	}
+
+	p.txnMu.Lock()
+	defer p.txnMu.Unlock()
+	if p.txn != nil {
+		return errors.New("transaction already begun")
+	}
+	p.txn = &txn{offsets: make(map[string][]sk.PartitionOffset)}
+	return nil
+}
+
+// CommitTxn writes messages and offsets of the transaction at once,
+// nothing is written if any message fails.
+func (p *Producer) CommitTxn() error {
+	p.txnMu.Lock()
+	t := p.txn
+	p.txn = nil
+	p.txnMu.Unlock()
+	if t == nil {
+		return ErrNoTxn
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	partitions, offsets, err := p.broker.produceTxn(t.msgs, t.offsets, func(msg *sk.ProducerMessage, numPartitions int32) (int32, error) {
+		return p.partition(msg, numPartitions), nil
+	})
+	if p.report != nil {
+		for i, msg := range t.sent {
+			r := sk.DeliveryReport{Message: msg, Partition: -1, Offset: -1, Err: err}
+			if err == nil {
+				r.Partition, r.Offset = partitions[i], offsets[i]
+			}
+			p.report(r)
+		}
+	}
+	return err
+}
+
+func (p *Producer) AbortTxn() error {
+	p.txnMu.Lock()
+	defer p.txnMu.Unlock()
+	if p.txn == nil {
+		return ErrNoTxn
+	}
+	p.txn = nil
+	return nil
+}
+
+func (p *Producer) SendOffsetsToTxn(offsets []sk.PartitionOffset, groupID string) error {
+	p.txnMu.Lock()
+	defer p.txnMu.Unlock()
+	if p.txn == nil {
+		return ErrNoTxn
+	}
+	p.txn.offsets[groupID] = append(p.txn.offsets[groupID], offsets...)
+	return nil
+}
+
+func copyMessage(msg *sk.ProducerMessage) *sk.ProducerMessage {
+	m := *msg
+	m.Key = clone(msg.Key)
+	m.Value = clone(msg.Value)
+	m.Headers = cloneHeaders(msg.Headers)
+	if msg.Partition != nil {
+		partition := *msg.Partition
+		m.Partition = &partition
+	}
+	return &m
+}
@@ -0,0 +1,48 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+func TestSeek(t *testing.T) {
+	b := memory.NewBroker(1)
+	p := newProducer(t, b, sk.ProducerConfig{})
+	start := time.Now()
+	produce(t, p, "test", 0, "m0", "m1", "m2")
+
+	c := newConsumer(t, b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first"})
+	testkit.Receive(t, c, 3)
+
+	if err := c.Seek(sk.PartitionOffset{Topic: "test", Partition: 0, Offset: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := testkit.Receive(t, c, 2); msgs[0].Offset() != 1 || msgs[1].Offset() != 2 {
+		t.Fatalf("got offsets %d, %d after seek, want 1, 2", msgs[0].Offset(), msgs[1].Offset())
+	}
+
+	if err := c.SeekTime(start); err != nil {
+		t.Fatal(err)
+	}
+	if msg := testkit.Receive(t, c, 1)[0]; msg.Offset() != 0 {
+		t.Fatalf("got offset %d after seek time, want 0", msg.Offset())
+	}
+	testkit.Receive(t, c, 2)
+
+	if err := c.Seek(sk.PartitionOffset{Topic: "test", Partition: 0, Offset: sk.LastOffset}); err != nil {
+		t.Fatal(err)
+	}
+	testkit.ReceiveNone(t, c, 10*time.Millisecond)
+	produce(t, p, "test", 0, "m3")
+	if v := string(testkit.Receive(t, c, 1)[0].Value()); v != "m3" {
+		t.Fatalf("got %s after seek to the last offset, want m3", v)
+	}
+
+	if err := c.Seek(sk.PartitionOffset{Topic: "test", Partition: 0, Offset: -5}); err == nil {
+		t.Fatal("seek to an invalid offset succeeded")
+	}
+}
@@ -0,0 +1,47 @@
+package kafka
+
+import "time"
+
+// MetricsInterval is how often clients report stats to Metrics,
+// they also report once on stop.
+const MetricsInterval = 10 * time.Second
+
+// Metrics receives stats of producers and consumers, counts are deltas
+// since the last report, averages are of recent requests of the client.
+type Metrics interface {
+	ReportProducer(s ProducerStats)
+	ReportConsumer(s ConsumerStats)
+	ReportPartition(s PartitionStats)
+}
+
+// ProducerStats are stats of a producer over all topics.
+type ProducerStats struct {
+	Messages int64
+	Bytes    int64
+	Errors   int64
+	// average messages per request
+	BatchSize float64
+	// average latency of produce requests
+	Latency time.Duration
+}
+
+// ConsumerStats are stats of a consumer over all topics.
+type ConsumerStats struct {
+	Fetches int64
+	Errors  int64
+	// average messages per fetch
+	BatchSize float64
+	// average latency of fetch requests
+	Latency time.Duration
+}
+
+// PartitionStats are stats of a partition read by a consumer.
+type PartitionStats struct {
+	Topic     string
+	Partition int32
+	Messages  int64
+	Bytes     int64
+	// Lag is the number of messages behind the high water mark,
+	// it is -1 if unknown.
+	Lag int64
+}
@@ -0,0 +1,122 @@
+// Package prometheus implements sk.Metrics by Prometheus collectors.
+//
+// Use prometheus.WrapRegistererWith to tell clients apart by labels,
+// e.g. a producer and a consumer of the same process.
+package prometheus
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sk "github.com/sko00o/kafka"
+)
+
+const namespace = "kafka"
+
+// Metrics implements sk.Metrics.
+type Metrics struct {
+	producerMessages  prometheus.Counter
+	producerBytes     prometheus.Counter
+	producerErrors    prometheus.Counter
+	producerBatchSize prometheus.Gauge
+	producerLatency   prometheus.Gauge
+
+	consumerFetches   prometheus.Counter
+	consumerErrors    prometheus.Counter
+	consumerBatchSize prometheus.Gauge
+	consumerLatency   prometheus.Gauge
+
+	consumerMessages *prometheus.CounterVec
+	consumerBytes    *prometheus.CounterVec
+	consumerLag      *prometheus.GaugeVec
+}
+
+// New registers metrics to reg, prometheus.DefaultRegisterer if it is nil.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	counter := func(subsystem, name, help string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem, Name: name, Help: help,
+		})
+	}
+	gauge := func(subsystem, name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: subsystem, Name: name, Help: help,
+		})
+	}
+	partitionLabels := []string{"topic", "partition"}
+
+	m := &Metrics{
+		producerMessages:  counter("producer", "messages_total", "Messages produced."),
+		producerBytes:     counter("producer", "bytes_total", "Bytes produced."),
+		producerErrors:    counter("producer", "errors_total", "Errors of producing."),
+		producerBatchSize: gauge("producer", "batch_size", "Average messages per produce request."),
+		producerLatency:   gauge("producer", "latency_seconds", "Average latency of produce requests."),
+
+		consumerFetches:   counter("consumer", "fetches_total", "Fetch requests."),
+		consumerErrors:    counter("consumer", "errors_total", "Errors of consuming."),
+		consumerBatchSize: gauge("consumer", "batch_size", "Average messages per fetch."),
+		consumerLatency:   gauge("consumer", "latency_seconds", "Average latency of fetch requests."),
+
+		consumerMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_total", Help: "Messages consumed.",
+		}, partitionLabels),
+		consumerBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "bytes_total", Help: "Bytes of keys and values consumed.",
+		}, partitionLabels),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "lag", Help: "Messages behind the high water mark.",
+		}, partitionLabels),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.producerMessages, m.producerBytes, m.producerErrors, m.producerBatchSize, m.producerLatency,
+		m.consumerFetches, m.consumerErrors, m.consumerBatchSize, m.consumerLatency,
+		m.consumerMessages, m.consumerBytes, m.consumerLag,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("register metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) ReportProducer(s sk.ProducerStats) {
+	m.producerMessages.Add(float64(s.Messages))
+	m.producerBytes.Add(float64(s.Bytes))
+	m.producerErrors.Add(float64(s.Errors))
+	// NOTE: keep the last averages while idle
+	if s.BatchSize > 0 {
+		m.producerBatchSize.Set(s.BatchSize)
+	}
+	if s.Latency > 0 {
+		m.producerLatency.Set(s.Latency.Seconds())
+	}
+}
+
+func (m *Metrics) ReportConsumer(s sk.ConsumerStats) {
+	m.consumerFetches.Add(float64(s.Fetches))
+	m.consumerErrors.Add(float64(s.Errors))
+	if s.BatchSize > 0 {
+		m.consumerBatchSize.Set(s.BatchSize)
+	}
+	if s.Latency > 0 {
+		m.consumerLatency.Set(s.Latency.Seconds())
+	}
+}
+
+func (m *Metrics) ReportPartition(s sk.PartitionStats) {
+	labels := prometheus.Labels{
+		"topic":     s.Topic,
+		"partition": strconv.Itoa(int(s.Partition)),
+	}
+	m.consumerMessages.With(labels).Add(float64(s.Messages))
+	m.consumerBytes.With(labels).Add(float64(s.Bytes))
+	if s.Lag >= 0 {
+		m.consumerLag.With(labels).Set(float64(s.Lag))
+	}
+}
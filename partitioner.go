@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Partitioner chooses the partition of a message without an explicit one,
+// the result must be in [0, numPartitions). It is shared by the backends,
+// so a message is placed on the same partition whichever client sends it.
+type Partitioner interface {
+	Partition(msg *ProducerMessage, numPartitions int32) int32
+}
+
+// PartitionerFunc is a stateless Partitioner.
+type PartitionerFunc func(msg *ProducerMessage, numPartitions int32) int32
+
+func (f PartitionerFunc) Partition(msg *ProducerMessage, numPartitions int32) int32 {
+	return f(msg, numPartitions)
+}
+
+// PartitionerConstructor creates the Partitioner of a topic,
+// it may be called concurrently with the Partitioner in use.
+type PartitionerConstructor func(topic string) Partitioner
+
+var (
+	partitionersMu sync.RWMutex
+	partitioners   = map[string]PartitionerConstructor{
+		"roundrobin": func(string) Partitioner { return new(roundRobin) },
+		"hash":       keyHash(fnvHash, false),
+		"murmur2":    keyHash(murmur2Hash, false),
+		"crc32":      keyHash(crc32Hash, false),
+		"manual":     func(string) Partitioner { return PartitionerFunc(manual) },
+		"sticky":     func(string) Partitioner { return new(sticky) },
+	}
+	// hashes of the built-in partitioners, to apply balancer_consistent
+	hashes = map[string]func(key []byte, numPartitions int32) int32{
+		"hash":    fnvHash,
+		"murmur2": murmur2Hash,
+		"crc32":   crc32Hash,
+	}
+)
+
+// RegisterPartitioner makes a partitioner available as balancer name,
+// it panics if the name is registered twice.
+func RegisterPartitioner(name string, f PartitionerConstructor) {
+	partitionersMu.Lock()
+	defer partitionersMu.Unlock()
+
+	if f == nil {
+		panic("kafka: register partitioner is nil")
+	}
+	if _, dup := partitioners[name]; dup {
+		panic("kafka: register partitioner twice for " + name)
+	}
+	partitioners[name] = f
+}
+
+// Partitioners returns the sorted names of registered partitioners.
+func Partitioners() []string {
+	partitionersMu.RLock()
+	defer partitionersMu.RUnlock()
+
+	names := make([]string, 0, len(partitioners))
+	for name := range partitioners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPartitioner returns the partitioner of balancer in c,
+// roundrobin if it is empty.
+func NewPartitioner(c ProducerConfig) (PartitionerConstructor, error) {
+	name := c.Balancer
+	if name == "" {
+		name = "roundrobin"
+	}
+	if h, ok := hashes[name]; ok {
+		return keyHash(h, c.BalancerConsistent), nil
+	}
+
+	partitionersMu.RLock()
+	f, ok := partitioners[name]
+	partitionersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("balancer %s not support", name)
+	}
+	return f, nil
+}
+
+// IsHashBalancer reports whether balancer places messages by a hash of
+// their key, so a key always goes to the same partition.
+func IsHashBalancer(balancer string) bool {
+	_, ok := hashes[balancer]
+	return ok
+}
+
+type roundRobin struct {
+	counter uint32
+}
+
+func (p *roundRobin) Partition(_ *ProducerMessage, numPartitions int32) int32 {
+	n := atomic.AddUint32(&p.counter, 1) - 1
+	return int32(n % uint32(numPartitions))
+}
+
+// keyHash places messages by hash of the key, messages without key go
+// round-robin, or are hashed as an empty key if consistent.
+func keyHash(hash func(key []byte, numPartitions int32) int32, consistent bool) PartitionerConstructor {
+	return func(string) Partitioner {
+		rr := new(roundRobin)
+		return PartitionerFunc(func(msg *ProducerMessage, numPartitions int32) int32 {
+			if msg.Key == nil && !consistent {
+				return rr.Partition(msg, numPartitions)
+			}
+			return hash(msg.Key, numPartitions)
+		})
+	}
+}
+
+// fnvHash is the same as the default hash partitioner of sarama.
+func fnvHash(key []byte, numPartitions int32) int32 {
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	partition := int32(h.Sum32()) % numPartitions
+	if partition < 0 {
+		partition = -partition
+	}
+	return partition
+}
+
+// crc32Hash is the same as the consistent partitioner of librdkafka.
+func crc32Hash(key []byte, numPartitions int32) int32 {
+	return int32(crc32.ChecksumIEEE(key) % uint32(numPartitions))
+}
+
+// murmur2Hash is the same as the default partitioner of the Java client.
+func murmur2Hash(key []byte, numPartitions int32) int32 {
+	return int32(murmur2(key)&0x7fffffff) % numPartitions
+}
+
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// manual places messages without a partition on partition 0,
+// same as the manual partitioner of sarama.
+func manual(msg *ProducerMessage, _ int32) int32 {
+	if msg.Partition != nil {
+		return *msg.Partition
+	}
+	return 0
+}
+
+// stickyMessages is how many messages without key stay on a partition,
+// same as the default batch_size of kafka-go.
+const stickyMessages = 100
+
+// sticky places messages by murmur2 of the key, messages without key
+// stick to a partition for stickyMessages, so they fill up batches.
+type sticky struct {
+	counter uint32
+}
+
+func (p *sticky) Partition(msg *ProducerMessage, numPartitions int32) int32 {
+	if msg.Key != nil {
+		return murmur2Hash(msg.Key, numPartitions)
+	}
+	n := atomic.AddUint32(&p.counter, 1) - 1
+	return int32(n / stickyMessages % uint32(numPartitions))
+}
@@ -0,0 +1,32 @@
+package kafkago
+
+import (
+	sk "github.com/sko00o/kafka"
+)
+
+// Backend is the name of this producer in the backend registry.
+const Backend = "kafkago"
+
+func init() {
+	sk.RegisterProducer(Backend, func(c sk.ProducerConfig, o sk.Options) (sk.Producer, error) {
+		var options []OptionFunc
+		if o.Logger != nil {
+			options = append(options, WithLogger(o.Logger))
+		}
+		if o.Metrics != nil {
+			options = append(options, WithMetrics(o.Metrics))
+		}
+		if o.TokenProvider != nil {
+			options = append(options, WithTokenProvider(o.TokenProvider))
+		}
+		if o.DeliveryReport != nil {
+			options = append(options, WithDeliveryReport(o.DeliveryReport))
+		}
+
+		h, err := New(c, options...)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	})
+}
@@ -2,27 +2,41 @@ package kafkago
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/compress"
-	"github.com/segmentio/kafka-go/sasl"
-	"github.com/segmentio/kafka-go/sasl/plain"
-	"github.com/segmentio/kafka-go/sasl/scram"
 	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
 )
 
 type Handler struct {
 	Producer
-	log Logger
+	log    Logger
+	report func(sk.DeliveryReport)
+
+	metrics sk.Metrics
+	// bytes of keys and values delivered since the last report
+	bytes int64
+	// stopMetrics stops reporting and makes the last report
+	stopMetrics func()
+
+	tokenProvider sasl.TokenProvider
 }
 
 // New creates a new kafka producer
 func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
+	if c.Idempotent {
+		return nil, errors.New("idempotent not support by kafka-go, use sarama backend")
+	}
+	if c.TransactionalID != "" {
+		return nil, errors.New("transactional_id not support by kafka-go, use sarama backend")
+	}
+
 	h := &Handler{}
 
 	// NOTE: we need to set logger, so we call OptionFunc here
@@ -66,47 +80,54 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 		w.WriteTimeout = v
 	}
 
-	if v := c.Balancer; v != "" {
-		consistent := c.BalancerConsistent
-		switch v {
-		case "leastbytes":
-			w.Balancer = &kafka.LeastBytes{}
-		case "murmur2":
-			w.Balancer = &kafka.Murmur2Balancer{Consistent: consistent}
-		case "crc32":
-			w.Balancer = &kafka.CRC32Balancer{Consistent: consistent}
-		default:
-			return nil, fmt.Errorf("unsupport balancer %s", v)
+	switch v := c.Balancer; v {
+	case "":
+	case "leastbytes":
+		w.Balancer = &kafka.LeastBytes{}
+	default:
+		constructor, err := sk.NewPartitioner(c)
+		if err != nil {
+			return nil, err
 		}
+		w.Balancer = &balancer{constructor: constructor}
+	}
+	if w.Balancer == nil {
+		// NOTE: same as the default balancer of kafka.Writer
+		w.Balancer = &kafka.RoundRobin{}
+	}
+	w.Balancer = manualBalancer{Balancer: w.Balancer}
+
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
 	}
-	if v := c.SASL; v != nil {
-		var mechanism sasl.Mechanism
-		var err error
-		switch strings.ToLower(v.Mechanism) {
-		case "plain":
-			mechanism = plain.Mechanism{
-				Username: v.Username,
-				Password: v.Password,
-			}
-		case "scram", "scram_sha_256":
-			mechanism, err = scram.Mechanism(scram.SHA256, v.Username, v.Password)
-			if err != nil {
-				return nil, fmt.Errorf("new mechanism scram_sha_256: %w", err)
-			}
-		case "scram_sha_512":
-			mechanism, err = scram.Mechanism(scram.SHA512, v.Username, v.Password)
-			if err != nil {
-				return nil, fmt.Errorf("new mechanism scram_sha_512: %w", err)
-			}
-		default:
-			return nil, fmt.Errorf("sasl mechanism %s not support", v.Mechanism)
-		}
 
+	mechanism, err := sasl.KafkaGoMechanism(c.SASL, h.tokenProvider)
+	if err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
+
+	if mechanism != nil || tlsCfg != nil {
 		w.Transport = &kafka.Transport{
 			Dial: (&net.Dialer{
 				Timeout: 3 * time.Second,
 			}).DialContext,
 			SASL: mechanism,
+			TLS:  tlsCfg,
+		}
+	}
+
+	if h.report != nil || h.metrics != nil {
+		w.Completion = func(msgs []kafka.Message, err error) {
+			if h.metrics != nil && err == nil {
+				h.countBytes(msgs)
+			}
+			if h.report == nil {
+				return
+			}
+			for i := range msgs {
+				h.report(deliveryReport(msgs[i], err))
+			}
 		}
 	}
 
@@ -122,6 +143,7 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 		writer = &batchWriter{
 			Writer: w,
 			log:    h.log,
+			report: h.report,
 			sema:   make(chan struct{}, v),
 			wg:     new(sync.WaitGroup),
 		}
@@ -134,6 +156,14 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 	}
 
 	h.Producer = producer
+	if h.metrics != nil {
+		done := make(chan struct{})
+		go h.reportMetrics(w, done)
+		h.stopMetrics = func() {
+			close(done)
+			h.reportStats(w)
+		}
+	}
 	return h, nil
 }
 
@@ -144,8 +174,15 @@ func (h *Handler) Stop() {
 			h.log.Errorf("stop producer: %v", err)
 		}
 	}
+	if h.stopMetrics != nil {
+		h.stopMetrics()
+	}
 }
 
 func (h *Handler) Send(topic string, value []byte) error {
 	return h.Producer.SendWithKey(topic, nil, value)
 }
+
+func (h *Handler) SendContext(ctx context.Context, topic string, value []byte) error {
+	return h.Producer.SendWithKeyContext(ctx, topic, nil, value)
+}
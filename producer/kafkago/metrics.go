@@ -0,0 +1,44 @@
+package kafkago
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
+)
+
+// countBytes counts keys and values of msgs delivered, kafka-go counts
+// the encoded size of them instead.
+func (h *Handler) countBytes(msgs []kafka.Message) {
+	var n int
+	for i := range msgs {
+		n += len(msgs[i].Key) + len(msgs[i].Value)
+	}
+	atomic.AddInt64(&h.bytes, int64(n))
+}
+
+// reportMetrics reports stats of w every sk.MetricsInterval until done is closed.
+func (h *Handler) reportMetrics(w *kafka.Writer, done <-chan struct{}) {
+	ticker := time.NewTicker(sk.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.reportStats(w)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (h *Handler) reportStats(w *kafka.Writer) {
+	s := w.Stats()
+	h.metrics.ReportProducer(sk.ProducerStats{
+		Messages:  s.Messages,
+		Bytes:     atomic.SwapInt64(&h.bytes, 0),
+		Errors:    s.Errors,
+		BatchSize: float64(s.BatchSize.Avg),
+		Latency:   s.WriteTime.Avg,
+	})
+}
@@ -2,14 +2,18 @@ package kafkago
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"github.com/segmentio/kafka-go"
+	sk "github.com/sko00o/kafka"
 )
 
 type Producer interface {
 	Close() error
 	SendWithKey(topic string, key, value []byte) error
+	SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error
+	SendMessage(ctx context.Context, msg *sk.ProducerMessage) error
 }
 
 type Writer interface {
@@ -35,17 +39,84 @@ func (p *SimpleKafkaGoProducer) protectMsg(msg []byte) []byte {
 }
 
 func (p *SimpleKafkaGoProducer) SendWithKey(topic string, key, value []byte) error {
-	msg := kafka.Message{
+	return p.SendWithKeyContext(p.ctx, topic, key, value)
+}
+
+func (p *SimpleKafkaGoProducer) SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{
 		Topic: topic,
 		Key:   key,
-		Value: p.protectMsg(value),
+		Value: value,
+	})
+}
+
+func (p *SimpleKafkaGoProducer) SendMessage(ctx context.Context, msg *sk.ProducerMessage) error {
+	m := kafka.Message{
+		Topic:      msg.Topic,
+		Key:        msg.Key,
+		Value:      p.protectMsg(msg.Value),
+		Time:       msg.Timestamp,
+		WriterData: msg,
+	}
+	if len(msg.Headers) > 0 {
+		m.Headers = make([]kafka.Header, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			m.Headers = append(m.Headers, kafka.Header{
+				Key:   h.Key,
+				Value: h.Value,
+			})
+		}
+	}
+	return p.Writer.WriteMessages(ctx, m)
+}
+
+// manualBalancer places messages with an explicit partition,
+// the others are balanced by the wrapped Balancer.
+type manualBalancer struct {
+	kafka.Balancer
+}
+
+func (b manualBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if m, ok := msg.WriterData.(*sk.ProducerMessage); ok && m.Partition != nil {
+		return int(*m.Partition)
+	}
+	return b.Balancer.Balance(msg, partitions...)
+}
+
+// balancer adapts sk.Partitioner to kafka-go,
+// with a Partitioner per topic.
+type balancer struct {
+	constructor sk.PartitionerConstructor
+
+	mu     sync.Mutex
+	topics map[string]sk.Partitioner
+}
+
+func (b *balancer) Balance(msg kafka.Message, partitions ...int) int {
+	m, ok := msg.WriterData.(*sk.ProducerMessage)
+	if !ok {
+		m = &sk.ProducerMessage{Topic: msg.Topic, Key: msg.Key}
+	}
+
+	b.mu.Lock()
+	p, ok := b.topics[m.Topic]
+	if !ok {
+		if b.topics == nil {
+			b.topics = make(map[string]sk.Partitioner)
+		}
+		p = b.constructor(m.Topic)
+		b.topics[m.Topic] = p
 	}
-	return p.Writer.WriteMessages(p.ctx, msg)
+	b.mu.Unlock()
+
+	// NOTE: partitions are all of the topic, from 0 to n-1
+	return int(p.Partition(m, int32(len(partitions))))
 }
 
 type batchWriter struct {
 	*kafka.Writer
-	log Logger
+	log    Logger
+	report func(sk.DeliveryReport)
 
 	// limit max goroutine number
 	sema chan struct{}
@@ -55,17 +126,33 @@ type batchWriter struct {
 
 func (w *batchWriter) WriteMessages(ctx context.Context, msg ...kafka.Message) error {
 	w.wg.Add(1)
-	w.sema <- struct{}{}
+	select {
+	case w.sema <- struct{}{}:
+	case <-ctx.Done():
+		w.wg.Done()
+		return ctx.Err()
+	}
 	go func() {
 		defer func() {
 			<-w.sema
 			w.wg.Done()
 		}()
 
-		if err := w.Writer.WriteMessages(ctx, msg...); err != nil {
+		// NOTE: ctx only limits the wait for a free slot,
+		// the write itself outlives the caller.
+		if err := w.Writer.WriteMessages(context.Background(), msg...); err != nil {
 			if w.log != nil {
 				w.log.Errorf("write messages: %v", err)
 			}
+
+			// NOTE: failed batches are reported by the Completion of writer,
+			// other errors happen before messages get into any batch.
+			var wErr kafka.WriteErrors
+			if w.report != nil && !errors.As(err, &wErr) {
+				for i := range msg {
+					w.report(deliveryReport(msg[i], err))
+				}
+			}
 		}
 	}()
 	return nil
@@ -76,3 +163,34 @@ func (w *batchWriter) Close() error {
 	close(w.sema)
 	return w.Writer.Close()
 }
+
+func deliveryReport(msg kafka.Message, err error) sk.DeliveryReport {
+	m, ok := msg.WriterData.(*sk.ProducerMessage)
+	if !ok {
+		m = &sk.ProducerMessage{
+			Topic:     msg.Topic,
+			Key:       msg.Key,
+			Value:     msg.Value,
+			Timestamp: msg.Time,
+		}
+		for _, h := range msg.Headers {
+			m.Headers = append(m.Headers, sk.Header{
+				Key:   h.Key,
+				Value: h.Value,
+			})
+		}
+	}
+
+	r := sk.DeliveryReport{
+		Message:   m,
+		Partition: int32(msg.Partition),
+		Offset:    msg.Offset,
+		Err:       err,
+	}
+	if err != nil {
+		// NOTE: kafka-go leaves them unset for failed messages
+		r.Partition = -1
+		r.Offset = -1
+	}
+	return r
+}
@@ -1,16 +1,34 @@
 package sarama
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/Shopify/sarama"
 	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
 )
 
 type Handler struct {
 	Producer
-	log Logger
+	log    Logger
+	report func(sk.DeliveryReport)
+	txn    txnProducer
+
+	// async is closed by AsyncClose when reports are read,
+	// reports waits for readers of its errors and successes
+	async   sarama.AsyncProducer
+	reports sync.WaitGroup
+
+	metrics sk.Metrics
+	stats   stats
+	// stopMetrics stops reporting and makes the last report
+	stopMetrics func()
+
+	tokenProvider sasl.TokenProvider
 }
 
 // New creates a new kafka producer
@@ -26,6 +44,14 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 
 	cfg := sarama.NewConfig()
 	cfg.Producer.RequiredAcks = sarama.RequiredAcks(c.RequiredAcks)
+	if v := c.Balancer; v != "" {
+		constructor, err := sk.NewPartitioner(c)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Producer.Partitioner = partitionerConstructor(constructor, c)
+	}
+	cfg.Producer.Partitioner = withManualPartition(cfg.Producer.Partitioner)
 
 	if v := c.Version; v != "" {
 		version, err := sarama.ParseKafkaVersion(v)
@@ -78,27 +104,39 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 		cfg.Net.WriteTimeout = v
 	}
 
-	if v := c.SASL; v != nil {
-		cfg.Net.SASL.Enable = true
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	if tlsCfg != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsCfg
+	}
+
+	if err := sasl.ConfigureSarama(cfg, c.SASL, h.tokenProvider); err != nil {
+		return nil, fmt.Errorf("sasl config: %w", err)
+	}
 
-		switch strings.ToLower(v.Mechanism) {
-		case "plain":
-			cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
-		case "scram", "scram_sha_256":
-			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
-		case "scram_sha_512":
-			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
-		default:
-			return nil, fmt.Errorf("sasl machanism %s not support", v.Mechanism)
+	if c.Idempotent || c.TransactionalID != "" {
+		if err := checkIdempotent(c, cfg.Version); err != nil {
+			return nil, err
 		}
+		cfg.Producer.Idempotent = true
+		cfg.Producer.RequiredAcks = sarama.WaitForAll
+		cfg.Net.MaxOpenRequests = 1
+	}
+	cfg.Producer.Transaction.ID = c.TransactionalID
 
-		cfg.Net.SASL.User = v.Username
-		cfg.Net.SASL.Password = v.Password
+	if h.metrics != nil {
+		// NOTE: errors and bytes are counted by delivery reports
+		h.report = h.countReports(h.report)
+		h.stats.registry = cfg.MetricRegistry
+		h.stats.meters.Registry = cfg.MetricRegistry
 	}
 
 	if c.Async {
-		cfg.Producer.Return.Successes = false
-		cfg.Producer.Return.Errors = c.EnableAsyncErrors
+		cfg.Producer.Return.Successes = h.report != nil
+		cfg.Producer.Return.Errors = c.EnableAsyncErrors || h.report != nil
 	} else {
 		cfg.Producer.Return.Successes = true
 		cfg.Producer.Return.Errors = true
@@ -115,14 +153,32 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 			return nil, fmt.Errorf("new async producer: %w", err)
 		}
 		producer = &SimpleAsyncProducer{AsyncProducer: p}
+		h.txn = p
 
-		if c.EnableAsyncErrors {
+		if cfg.Producer.Return.Errors || cfg.Producer.Return.Successes {
+			h.async = p
+		}
+		if cfg.Producer.Return.Errors {
 			// Track errors
+			h.reports.Add(1)
 			go func() {
+				defer h.reports.Done()
 				for err := range p.Errors() {
-					if h.log != nil {
+					if c.EnableAsyncErrors && h.log != nil {
 						h.log.Errorf("producer: %s", err.Error())
 					}
+					if h.report != nil {
+						h.report(deliveryReport(err.Msg, err.Err))
+					}
+				}
+			}()
+		}
+		if cfg.Producer.Return.Successes {
+			h.reports.Add(1)
+			go func() {
+				defer h.reports.Done()
+				for msg := range p.Successes() {
+					h.report(deliveryReport(msg, nil))
 				}
 			}()
 		}
@@ -131,22 +187,110 @@ func New(c sk.ProducerConfig, options ...OptionFunc) (*Handler, error) {
 		if err != nil {
 			return nil, fmt.Errorf("new sync producer: %w", err)
 		}
-		producer = &SimpleSyncProducer{SyncProducer: p}
+		producer = &SimpleSyncProducer{
+			SyncProducer: p,
+			report:       h.report,
+		}
+		h.txn = p
 	}
 
 	h.Producer = producer
+	if h.metrics != nil {
+		done := make(chan struct{})
+		go h.reportMetrics(done)
+		h.stopMetrics = func() {
+			close(done)
+			h.reportStats()
+		}
+	}
 	return h, nil
 }
 
 func (h *Handler) Stop() {
-	if err := h.Producer.Close(); err != nil {
+	if h.async != nil {
+		// NOTE: Close drains errors and successes itself,
+		// reports of in-flight messages would be lost
+		h.async.AsyncClose()
+		h.reports.Wait()
+	} else if err := h.Producer.Close(); err != nil {
 		// will not get error actually
 		if h.log != nil {
 			h.log.Errorf("stop producer: %v", err)
 		}
 	}
+	if h.stopMetrics != nil {
+		h.stopMetrics()
+	}
 }
 
 func (h *Handler) Send(topic string, value []byte) error {
 	return h.Producer.SendWithKey(topic, nil, value)
 }
+
+func (h *Handler) SendContext(ctx context.Context, topic string, value []byte) error {
+	return h.Producer.SendWithKeyContext(ctx, topic, nil, value)
+}
+
+// checkIdempotent returns the settings of c conflicting with the
+// idempotent producer, required_acks 0 is taken as unset and upgraded
+// to -1 (all) by the caller.
+func checkIdempotent(c sk.ProducerConfig, version sarama.KafkaVersion) error {
+	if !version.IsAtLeast(sarama.V0_11_0_0) {
+		return fmt.Errorf("idempotent producer requires version >= 0.11.0.0, got %s", version)
+	}
+	if v := c.RequiredAcks; v != 0 && v != int(sarama.WaitForAll) {
+		return fmt.Errorf("idempotent producer requires required_acks -1 (all), got %d", v)
+	}
+	if v := c.MaxAttempts; v < 0 {
+		return fmt.Errorf("idempotent producer requires max_attempts >= 0 (0 for the default), got %d", v)
+	}
+	return nil
+}
+
+// txnProducer is implemented by both sarama producers.
+type txnProducer interface {
+	IsTransactional() bool
+	BeginTxn() error
+	CommitTxn() error
+	AbortTxn() error
+	AddOffsetsToTxn(offsets map[string][]*sarama.PartitionOffsetMetadata, groupID string) error
+}
+
+var errNotTransactional = errors.New("transactional_id is empty")
+
+// BeginTxn implements sk.TxnProducer.
+func (h *Handler) BeginTxn() error {
+	if !h.txn.IsTransactional() {
+		return errNotTransactional
+	}
+	return h.txn.BeginTxn()
+}
+
+func (h *Handler) CommitTxn() error {
+	if !h.txn.IsTransactional() {
+		return errNotTransactional
+	}
+	return h.txn.CommitTxn()
+}
+
+func (h *Handler) AbortTxn() error {
+	if !h.txn.IsTransactional() {
+		return errNotTransactional
+	}
+	return h.txn.AbortTxn()
+}
+
+func (h *Handler) SendOffsetsToTxn(offsets []sk.PartitionOffset, groupID string) error {
+	if !h.txn.IsTransactional() {
+		return errNotTransactional
+	}
+
+	m := make(map[string][]*sarama.PartitionOffsetMetadata)
+	for _, o := range offsets {
+		m[o.Topic] = append(m[o.Topic], &sarama.PartitionOffsetMetadata{
+			Partition: o.Partition,
+			Offset:    o.Offset,
+		})
+	}
+	return h.txn.AddOffsetsToTxn(m, groupID)
+}
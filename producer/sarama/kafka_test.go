@@ -0,0 +1,162 @@
+package sarama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+)
+
+func newMockBroker(t *testing.T, topic string) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()),
+		// NOTE: version 3 is sent by the default version 1.0.0,
+		// partition 1 fails without retry
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3).
+			SetError(topic, 1, sarama.ErrMessageSizeTooLarge),
+	})
+	return broker
+}
+
+func TestStopReportsInFlightMessages(t *testing.T) {
+	const (
+		topic = "test"
+		n     = 200
+	)
+	broker := newMockBroker(t, topic)
+
+	var (
+		mu      sync.Mutex
+		reports = make(map[string]int)
+		failed  int
+	)
+	h, err := New(sk.ProducerConfig{
+		Addresses: []string{broker.Addr()},
+		Async:     true,
+		// NOTE: errors are unknown without acks
+		RequiredAcks: 1,
+		Balancer:     "manual",
+	}, WithDeliveryReport(func(r sk.DeliveryReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports[string(r.Message.Value)]++
+		if r.Err != nil {
+			failed++
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < n; i++ {
+		partition := int32(i % 2)
+		if err := h.SendMessage(context.Background(), &sk.ProducerMessage{
+			Topic:     topic,
+			Partition: &partition,
+			Value:     []byte(fmt.Sprint(i)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != n {
+		t.Fatalf("got reports of %d messages, want %d", len(reports), n)
+	}
+	for v, cnt := range reports {
+		if cnt != 1 {
+			t.Errorf("message %s reported %d times", v, cnt)
+		}
+	}
+	if failed != n/2 {
+		t.Errorf("got %d failed reports, want %d", failed, n/2)
+	}
+}
+
+// producerMetrics sums reports of a producer.
+type producerMetrics struct {
+	sk.Metrics
+	mu    sync.Mutex
+	stats sk.ProducerStats
+}
+
+func (m *producerMetrics) ReportProducer(s sk.ProducerStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Messages += s.Messages
+	m.stats.Bytes += s.Bytes
+	m.stats.Errors += s.Errors
+}
+
+func TestMetricsCountKeysAndValues(t *testing.T) {
+	const (
+		topic = "test"
+		n     = 10
+	)
+	broker := newMockBroker(t, topic)
+
+	m := &producerMetrics{}
+	h, err := New(sk.ProducerConfig{
+		Addresses:    []string{broker.Addr()},
+		Async:        true,
+		RequiredAcks: 1,
+		Balancer:     "manual",
+	}, WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		partition := int32(i % 2)
+		if err := h.SendMessage(context.Background(), &sk.ProducerMessage{
+			Topic:     topic,
+			Partition: &partition,
+			Key:       []byte("k"),
+			Value:     []byte("vv"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// NOTE: bytes are of the delivered messages of partition 0
+	if m.stats.Bytes != n/2*3 {
+		t.Errorf("got %d bytes, want %d", m.stats.Bytes, n/2*3)
+	}
+	if m.stats.Errors != n/2 {
+		t.Errorf("got %d errors, want %d", m.stats.Errors, n/2)
+	}
+}
+
+func TestCheckIdempotent(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  sk.ProducerConfig
+		version sarama.KafkaVersion
+		ok      bool
+	}{
+		{"defaults", sk.ProducerConfig{}, sarama.V2_5_0_0, true},
+		{"acks all", sk.ProducerConfig{RequiredAcks: -1, MaxAttempts: 1}, sarama.V2_5_0_0, true},
+		{"acks leader", sk.ProducerConfig{RequiredAcks: 1}, sarama.V2_5_0_0, false},
+		{"negative attempts", sk.ProducerConfig{MaxAttempts: -1}, sarama.V2_5_0_0, false},
+		{"old version", sk.ProducerConfig{}, sarama.V0_10_2_0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkIdempotent(tc.config, tc.version)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
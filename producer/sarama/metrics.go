@@ -0,0 +1,58 @@
+package sarama
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/gometrics"
+)
+
+// stats are read from the go-metrics registry of sarama, errors and
+// bytes are counted by delivery reports.
+type stats struct {
+	registry metrics.Registry
+	meters   gometrics.Meters
+	errors   int64
+	bytes    int64
+}
+
+// countReports wraps report to count failed messages, and bytes of keys
+// and values of the delivered ones.
+func (h *Handler) countReports(report func(sk.DeliveryReport)) func(sk.DeliveryReport) {
+	return func(r sk.DeliveryReport) {
+		if r.Err != nil {
+			atomic.AddInt64(&h.stats.errors, 1)
+		} else {
+			atomic.AddInt64(&h.stats.bytes, int64(len(r.Message.Key)+len(r.Message.Value)))
+		}
+		if report != nil {
+			report(r)
+		}
+	}
+}
+
+// reportMetrics reports stats every sk.MetricsInterval until done is closed.
+func (h *Handler) reportMetrics(done <-chan struct{}) {
+	ticker := time.NewTicker(sk.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.reportStats()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (h *Handler) reportStats() {
+	h.metrics.ReportProducer(sk.ProducerStats{
+		Messages:  h.stats.meters.Delta("record-send-rate"),
+		Bytes:     atomic.SwapInt64(&h.stats.bytes, 0),
+		Errors:    atomic.SwapInt64(&h.stats.errors, 0),
+		BatchSize: gometrics.Mean(h.stats.registry, "records-per-request"),
+		Latency:   time.Duration(gometrics.Mean(h.stats.registry, "request-latency-in-ms") * float64(time.Millisecond)),
+	})
+}
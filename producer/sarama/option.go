@@ -1,5 +1,10 @@
 package sarama
 
+import (
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/sasl"
+)
+
 type OptionFunc func(*Handler) error
 
 func WithLogger(log Logger) OptionFunc {
@@ -8,3 +13,29 @@ func WithLogger(log Logger) OptionFunc {
 		return nil
 	}
 }
+
+// WithMetrics reports stats to m every sk.MetricsInterval and on stop.
+func WithMetrics(m sk.Metrics) OptionFunc {
+	return func(h *Handler) error {
+		h.metrics = m
+		return nil
+	}
+}
+
+// WithDeliveryReport sets fn to receive the result of every message,
+// fn should not block as it runs on the goroutine of the client.
+func WithDeliveryReport(fn func(sk.DeliveryReport)) OptionFunc {
+	return func(h *Handler) error {
+		h.report = fn
+		return nil
+	}
+}
+
+// WithTokenProvider sets the token provider of the oauthbearer mechanism,
+// it overrides the token settings of config.
+func WithTokenProvider(p sasl.TokenProvider) OptionFunc {
+	return func(h *Handler) error {
+		h.tokenProvider = p
+		return nil
+	}
+}
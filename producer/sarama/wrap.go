@@ -2,32 +2,88 @@ package sarama
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
 )
 
 type Producer interface {
 	Close() error
 	SendWithKey(topic string, key, value []byte) error
+	SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error
+	SendMessage(ctx context.Context, msg *sk.ProducerMessage) error
 	SendMessages(msgs []*sarama.ProducerMessage) error
 }
 
 type SimpleSyncProducer struct {
 	sarama.SyncProducer
 	ProducerMessage
+	report func(sk.DeliveryReport)
 }
 
 func (p *SimpleSyncProducer) SendWithKey(topic string, key, value []byte) error {
-	msg := &sarama.ProducerMessage{
+	return p.SendWithKeyContext(context.Background(), topic, key, value)
+}
+
+func (p *SimpleSyncProducer) SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{
 		Topic: topic,
-		Value: sarama.ByteEncoder(value),
+		Key:   key,
+		Value: value,
+	})
+}
+
+// SendMessage waits for the delivery until ctx is done,
+// in that case msg may still be delivered later.
+func (p *SimpleSyncProducer) SendMessage(ctx context.Context, msg *sk.ProducerMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if key != nil {
-		msg.Key = sarama.ByteEncoder(key)
+
+	m := producerMessage(msg)
+	if ctx.Done() == nil {
+		return p.sendMessage(m)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- p.sendMessage(m)
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+func (p *SimpleSyncProducer) sendMessage(msg *sarama.ProducerMessage) error {
 	_, _, err := p.SyncProducer.SendMessage(msg)
+	if p.report != nil {
+		p.report(deliveryReport(msg, err))
+	}
+	return err
+}
+
+func (p *SimpleSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	err := p.SyncProducer.SendMessages(msgs)
+	if p.report == nil {
+		return err
+	}
+
+	failed := make(map[*sarama.ProducerMessage]error)
+	var pErrs sarama.ProducerErrors
+	if errors.As(err, &pErrs) {
+		for _, e := range pErrs {
+			failed[e.Msg] = e.Err
+		}
+	}
+	for _, msg := range msgs {
+		p.report(deliveryReport(msg, failed[msg]))
+	}
 	return err
 }
 
@@ -43,21 +99,162 @@ func (p *SimpleAsyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error
 	return nil
 }
 
-func (p *SimpleAsyncProducer) SendMessage(msg *sarama.ProducerMessage) error {
-	p.AsyncProducer.Input() <- msg
-	return nil
+func (p *SimpleAsyncProducer) SendMessage(ctx context.Context, msg *sk.ProducerMessage) error {
+	select {
+	case p.AsyncProducer.Input() <- producerMessage(msg):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (p *SimpleAsyncProducer) SendWithKey(topic string, key, value []byte) error {
-	msg := &sarama.ProducerMessage{
+	return p.SendWithKeyContext(context.Background(), topic, key, value)
+}
+
+func (p *SimpleAsyncProducer) SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{
 		Topic: topic,
-		Value: sarama.ByteEncoder(value),
+		Key:   key,
+		Value: value,
+	})
+}
+
+// producerMessage converts msg, which is kept as Metadata of the result.
+func producerMessage(msg *sk.ProducerMessage) *sarama.ProducerMessage {
+	m := &sarama.ProducerMessage{
+		Topic:     msg.Topic,
+		Value:     sarama.ByteEncoder(msg.Value),
+		Timestamp: msg.Timestamp,
+		Metadata:  msg,
+	}
+	if msg.Key != nil {
+		m.Key = sarama.ByteEncoder(msg.Key)
 	}
-	if key != nil {
-		msg.Key = sarama.ByteEncoder(key)
+	if msg.Partition != nil {
+		m.Partition = *msg.Partition
 	}
+	if len(msg.Headers) > 0 {
+		m.Headers = make([]sarama.RecordHeader, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			m.Headers = append(m.Headers, sarama.RecordHeader{
+				Key:   []byte(h.Key),
+				Value: h.Value,
+			})
+		}
+	}
+	return m
+}
 
-	return p.SendMessage(msg)
+func deliveryReport(msg *sarama.ProducerMessage, err error) sk.DeliveryReport {
+	m, ok := msg.Metadata.(*sk.ProducerMessage)
+	if !ok {
+		m = &sk.ProducerMessage{
+			Topic:     msg.Topic,
+			Timestamp: msg.Timestamp,
+		}
+		if msg.Key != nil {
+			m.Key, _ = msg.Key.Encode()
+		}
+		if msg.Value != nil {
+			m.Value, _ = msg.Value.Encode()
+		}
+		for _, h := range msg.Headers {
+			m.Headers = append(m.Headers, sk.Header{
+				Key:   string(h.Key),
+				Value: h.Value,
+			})
+		}
+	}
+
+	r := sk.DeliveryReport{
+		Message:   m,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Err:       err,
+	}
+	if err != nil {
+		r.Offset = -1
+	}
+	return r
+}
+
+// partitioner adapts sk.Partitioner to sarama, only messages placed by
+// key hash require consistency, so the others may be moved to another
+// partition on failure.
+type partitioner struct {
+	sk.Partitioner
+	hash bool
+	// keyless messages are hashed as well
+	consistent bool
+}
+
+func partitionerConstructor(constructor sk.PartitionerConstructor, c sk.ProducerConfig) sarama.PartitionerConstructor {
+	hash := sk.IsHashBalancer(c.Balancer)
+	return func(topic string) sarama.Partitioner {
+		return partitioner{
+			Partitioner: constructor(topic),
+			hash:        hash,
+			consistent:  c.BalancerConsistent,
+		}
+	}
+}
+
+func (p partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	m, ok := msg.Metadata.(*sk.ProducerMessage)
+	if !ok {
+		m = &sk.ProducerMessage{Topic: msg.Topic}
+		if msg.Key != nil {
+			key, err := msg.Key.Encode()
+			if err != nil {
+				return -1, err
+			}
+			m.Key = key
+		}
+	}
+	return p.Partitioner.Partition(m, numPartitions), nil
+}
+
+func (p partitioner) RequiresConsistency() bool {
+	return p.hash
+}
+
+func (p partitioner) MessageRequiresConsistency(msg *sarama.ProducerMessage) bool {
+	return p.hash && (msg.Key != nil || p.consistent)
+}
+
+// manualPartitioner places messages with an explicit partition,
+// the others are placed by the wrapped Partitioner.
+type manualPartitioner struct {
+	sarama.Partitioner
+}
+
+func withManualPartition(constructor sarama.PartitionerConstructor) sarama.PartitionerConstructor {
+	return func(topic string) sarama.Partitioner {
+		return &manualPartitioner{Partitioner: constructor(topic)}
+	}
+}
+
+func isManual(msg *sarama.ProducerMessage) bool {
+	m, ok := msg.Metadata.(*sk.ProducerMessage)
+	return ok && m.Partition != nil
+}
+
+func (p *manualPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if isManual(msg) {
+		return msg.Partition, nil
+	}
+	return p.Partitioner.Partition(msg, numPartitions)
+}
+
+func (p *manualPartitioner) MessageRequiresConsistency(msg *sarama.ProducerMessage) bool {
+	if isManual(msg) {
+		return true
+	}
+	if dp, ok := p.Partitioner.(sarama.DynamicConsistencyPartitioner); ok {
+		return dp.MessageRequiresConsistency(msg)
+	}
+	return p.Partitioner.RequiresConsistency()
 }
 
 type ProducerMessage struct{}
@@ -0,0 +1,42 @@
+package sarama
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+)
+
+func TestPartitionerConsistency(t *testing.T) {
+	keyed := &sarama.ProducerMessage{Topic: "test", Key: sarama.StringEncoder("key")}
+	keyless := &sarama.ProducerMessage{Topic: "test"}
+
+	for _, tc := range []struct {
+		config             sk.ProducerConfig
+		keyed, keyless     bool
+		requiresConsistent bool
+	}{
+		{sk.ProducerConfig{Balancer: "roundrobin"}, false, false, false},
+		{sk.ProducerConfig{Balancer: "sticky"}, false, false, false},
+		{sk.ProducerConfig{Balancer: "hash"}, true, false, true},
+		{sk.ProducerConfig{Balancer: "murmur2", BalancerConsistent: true}, true, true, true},
+		{sk.ProducerConfig{Balancer: "crc32"}, true, false, true},
+	} {
+		t.Run(tc.config.Balancer, func(t *testing.T) {
+			constructor, err := sk.NewPartitioner(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := partitionerConstructor(constructor, tc.config)("test").(sarama.DynamicConsistencyPartitioner)
+			if v := p.RequiresConsistency(); v != tc.requiresConsistent {
+				t.Errorf("requires consistency %v, want %v", v, tc.requiresConsistent)
+			}
+			if v := p.MessageRequiresConsistency(keyed); v != tc.keyed {
+				t.Errorf("keyed message requires consistency %v, want %v", v, tc.keyed)
+			}
+			if v := p.MessageRequiresConsistency(keyless); v != tc.keyless {
+				t.Errorf("keyless message requires consistency %v, want %v", v, tc.keyless)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package retry
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Consumer holds messages of retry topics until their delay passed,
+// without blocking the handler. The partition of a held message is
+// paused, later messages of it are held behind, and it is resumed once
+// they are all dispatched. Batch mode is not supported.
+type Consumer struct {
+	sk.Consumer
+	msgChan chan sk.Message
+	due     chan sk.TopicPartition
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+func NewConsumer(c sk.Consumer) *Consumer {
+	return &Consumer{
+		Consumer: c,
+		due:      make(chan sk.TopicPartition),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run runs the underlying consumer, Receive returns its messages then.
+func (c *Consumer) Run() error {
+	if err := c.Consumer.Run(); err != nil {
+		return err
+	}
+
+	in := c.Consumer.Receive()
+	c.msgChan = make(chan sk.Message, cap(in))
+	c.wg.Add(1)
+	go c.forward(in)
+	return nil
+}
+
+func (c *Consumer) Receive() <-chan sk.Message {
+	return c.msgChan
+}
+
+func (c *Consumer) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+		c.Consumer.Stop()
+		c.wg.Wait()
+	})
+}
+
+// holding is the messages held of a partition, the first one is
+// the next to be due.
+type holding struct {
+	msgs  []sk.Message
+	timer *time.Timer
+	// paused is set if the partition was paused by the holding
+	paused bool
+}
+
+func (c *Consumer) forward(in <-chan sk.Message) {
+	defer c.wg.Done()
+	defer close(c.msgChan)
+
+	held := make(map[sk.TopicPartition]*holding)
+	c.dispatch(in, held)
+	for _, h := range held {
+		h.timer.Stop()
+	}
+	// NOTE: held and later messages are dropped,
+	// until Stop of the inner consumer closes in
+	for range in {
+	}
+}
+
+// dispatch returns when in is closed or c is stopped.
+func (c *Consumer) dispatch(in <-chan sk.Message, held map[sk.TopicPartition]*holding) {
+	for {
+		select {
+		case msg, ok := <-in:
+			if !ok {
+				return
+			}
+			tp := sk.TopicPartition{Topic: msg.Topic(), Partition: msg.Partition()}
+			if h, ok := held[tp]; ok {
+				h.msgs = append(h.msgs, msg)
+				continue
+			}
+			if d := delay(msg); d > 0 {
+				held[tp] = c.hold(tp, msg, d)
+				continue
+			}
+			if !c.send(msg) {
+				return
+			}
+		case tp := <-c.due:
+			if !c.release(held, tp) {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Consumer) hold(tp sk.TopicPartition, msg sk.Message, d time.Duration) *holding {
+	h := &holding{msgs: []sk.Message{msg}, timer: c.after(tp, d)}
+	// NOTE: a partition paused by the caller is left paused
+	if !contains(c.Consumer.Paused(), tp) {
+		c.Consumer.Pause(tp)
+		h.paused = true
+	}
+	return h
+}
+
+// release dispatches held messages of tp that are due, it returns false
+// if c is stopped.
+func (c *Consumer) release(held map[sk.TopicPartition]*holding, tp sk.TopicPartition) bool {
+	h := held[tp]
+	for len(h.msgs) > 0 {
+		if d := delay(h.msgs[0]); d > 0 {
+			h.timer = c.after(tp, d)
+			return true
+		}
+		if !c.send(h.msgs[0]) {
+			return false
+		}
+		h.msgs = h.msgs[1:]
+	}
+	delete(held, tp)
+	if h.paused {
+		c.Consumer.Resume(tp)
+	}
+	return true
+}
+
+func (c *Consumer) after(tp sk.TopicPartition, d time.Duration) *time.Timer {
+	return time.AfterFunc(d, func() {
+		select {
+		case c.due <- tp:
+		case <-c.done:
+		}
+	})
+}
+
+func (c *Consumer) send(msg sk.Message) bool {
+	select {
+	case c.msgChan <- msg:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// delay returns how long msg is to be held by its not-before time.
+func delay(msg sk.Message) time.Duration {
+	v, ok := header(msg, HeaderNotBefore)
+	if !ok {
+		return 0
+	}
+	ms, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Until(time.UnixMilli(ms))
+}
+
+func contains(tps []sk.TopicPartition, tp sk.TopicPartition) bool {
+	for _, v := range tps {
+		if v == tp {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// Headers set on republished messages,
+// the original ones are kept through all stages.
+const (
+	HeaderTopic     = "x-original-topic"
+	HeaderPartition = "x-original-partition"
+	HeaderOffset    = "x-original-offset"
+	HeaderError     = "x-error"
+	HeaderAttempt   = "x-retry-attempt"
+	HeaderNotBefore = "x-retry-not-before"
+)
+
+// Topics returns the retry topics of c, which should be subscribed
+// by the consumer together with the source topics.
+func Topics(c *sk.RetryConfig) []string {
+	if c == nil {
+		return nil
+	}
+	topics := make([]string, 0, len(c.Stages))
+	for _, s := range c.Stages {
+		topics = append(topics, s.Topic)
+	}
+	return topics
+}
+
+// Handler wraps h, a message h failed on is republished by p to the next
+// retry stage, or to the dead letter topic after the last one. Messages
+// of a retry topic are to be received through Consumer, which holds them
+// until their delay passed. The error of h is
+// only returned if there is nowhere to republish the message, or
+// republishing failed.
+func Handler(p sk.Producer, c *sk.RetryConfig, h sk.HandlerFunc) (sk.HandlerFunc, error) {
+	if c == nil {
+		return h, nil
+	}
+	for i, s := range c.Stages {
+		if s.Topic == "" {
+			return nil, fmt.Errorf("topic of retry stage %d is empty", i)
+		}
+	}
+
+	r := &retrier{producer: p, config: *c, handler: h}
+	return r.handle, nil
+}
+
+type retrier struct {
+	producer sk.Producer
+	config   sk.RetryConfig
+	handler  sk.HandlerFunc
+}
+
+func (r *retrier) handle(ctx context.Context, msg sk.Message) error {
+	herr := r.handler(ctx, msg)
+	if herr == nil {
+		return nil
+	}
+
+	attempt := attemptOf(msg)
+	var (
+		topic     string
+		notBefore time.Time
+	)
+	switch {
+	case attempt < len(r.config.Stages):
+		s := r.config.Stages[attempt]
+		topic, notBefore = s.Topic, time.Now().Add(s.Delay)
+	case r.config.DeadLetterTopic != "":
+		topic = r.config.DeadLetterTopic
+	default:
+		return herr
+	}
+
+	out := &sk.ProducerMessage{
+		Topic:   topic,
+		Key:     msg.Key(),
+		Value:   msg.Value(),
+		Headers: headers(msg, herr, attempt+1, notBefore),
+	}
+	if err := r.producer.SendMessage(ctx, out); err != nil {
+		return fmt.Errorf("republish to %s: %w, handler error: %v", topic, err, herr)
+	}
+	return nil
+}
+
+func attemptOf(msg sk.Message) int {
+	v, ok := header(msg, HeaderAttempt)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(string(v))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func headers(msg sk.Message, herr error, attempt int, notBefore time.Time) []sk.Header {
+	hs := make([]sk.Header, 0, len(msg.Headers())+6)
+	for _, h := range msg.Headers() {
+		switch h.Key {
+		case HeaderError, HeaderAttempt, HeaderNotBefore:
+		default:
+			hs = append(hs, h)
+		}
+	}
+
+	// NOTE: the first failure sets the origin, later stages keep it.
+	if _, ok := header(msg, HeaderTopic); !ok {
+		hs = append(hs,
+			sk.Header{Key: HeaderTopic, Value: []byte(msg.Topic())},
+			sk.Header{Key: HeaderPartition, Value: []byte(strconv.FormatInt(int64(msg.Partition()), 10))},
+			sk.Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset(), 10))},
+		)
+	}
+	hs = append(hs,
+		sk.Header{Key: HeaderError, Value: []byte(herr.Error())},
+		sk.Header{Key: HeaderAttempt, Value: []byte(strconv.Itoa(attempt))},
+	)
+	if !notBefore.IsZero() {
+		hs = append(hs, sk.Header{Key: HeaderNotBefore, Value: []byte(strconv.FormatInt(notBefore.UnixMilli(), 10))})
+	}
+	return hs
+}
+
+func header(msg sk.Message, key string) ([]byte, bool) {
+	for _, h := range msg.Headers() {
+		if h.Key == key {
+			return h.Value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+const delayOfStage = 500 * time.Millisecond
+
+type handled struct {
+	value string
+	at    time.Time
+}
+
+func TestRetryToDeadLetterTopic(t *testing.T) {
+	b := memory.NewBroker(1)
+	rc := &sk.RetryConfig{
+		Stages:          []sk.RetryStage{{Topic: "in.retry", Delay: delayOfStage}},
+		DeadLetterTopic: "in.dlt",
+	}
+	p, err := memory.NewProducer(b, sk.ProducerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	var (
+		mu   sync.Mutex
+		logs []handled
+	)
+	handler, err := Handler(p, rc, func(_ context.Context, msg sk.Message) error {
+		mu.Lock()
+		logs = append(logs, handled{string(msg.Value()), time.Now()})
+		mu.Unlock()
+		if string(msg.Value()) == "bad" {
+			return errors.New("bad message")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handledOf := func(value string) []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		var at []time.Time
+		for _, l := range logs {
+			if l.value == value {
+				at = append(at, l.at)
+			}
+		}
+		return at
+	}
+
+	cfg := sk.ConsumerConfig{
+		Topics:      append([]string{"in"}, Topics(rc)...),
+		GroupID:     "group",
+		StartOffset: "first",
+		ManualAck:   true,
+		// NOTE: a single worker, which a held message must not block
+		WorkerCnt: 1,
+	}
+	mc, err := memory.NewConsumer(b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsumer(mc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- sk.Serve(ctx, c, cfg, handler)
+	}()
+
+	if err := p.Send("in", []byte("bad")); err != nil {
+		t.Fatal(err)
+	}
+	testkit.WaitFor(t, "the held retry", func() bool {
+		return b.HighWaterMark("in.retry", 0) == 1 && contains(c.Paused(), sk.TopicPartition{Topic: "in.retry"})
+	})
+
+	if err := p.Send("in", []byte("good")); err != nil {
+		t.Fatal(err)
+	}
+	testkit.WaitFor(t, "the good message", func() bool { return len(handledOf("good")) == 1 })
+	if n := len(handledOf("bad")); n != 1 {
+		t.Fatalf("bad message handled %d times before its delay", n)
+	}
+
+	testkit.WaitFor(t, "the dead letter", func() bool { return b.HighWaterMark("in.dlt", 0) == 1 })
+	at := handledOf("bad")
+	if len(at) != 2 {
+		t.Fatalf("bad message handled %d times, want 2", len(at))
+	}
+	// NOTE: not-before time is in milliseconds
+	if d := at[1].Sub(at[0]); d < delayOfStage-time.Millisecond {
+		t.Fatalf("bad message retried after %s, want %s", d, delayOfStage)
+	}
+	testkit.WaitFor(t, "the resume", func() bool { return len(c.Paused()) == 0 })
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+
+	dlt, err := memory.NewConsumer(b, sk.ConsumerConfig{Topics: []string{"in.dlt"}, StartOffset: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dlt.Run(); err != nil {
+		t.Fatal(err)
+	}
+	defer dlt.Stop()
+	var msg sk.Message
+	select {
+	case msg = <-dlt.Receive():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no dead letter received")
+	}
+	for key, want := range map[string]string{
+		HeaderTopic:     "in",
+		HeaderPartition: "0",
+		HeaderOffset:    "0",
+		HeaderError:     "bad message",
+		HeaderAttempt:   strconv.Itoa(2),
+	} {
+		if v, _ := header(msg, key); string(v) != want {
+			t.Errorf("got header %s %q, want %q", key, v, want)
+		}
+	}
+	if _, ok := header(msg, HeaderNotBefore); ok {
+		t.Error("dead letter has a not-before time")
+	}
+}
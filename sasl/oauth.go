@@ -0,0 +1,78 @@
+package sasl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	ksasl "github.com/segmentio/kafka-go/sasl"
+	sk "github.com/sko00o/kafka"
+)
+
+// sarama requires token requests not to block forever
+const tokenTimeout = 30 * time.Second
+
+// NewTokenProvider creates the TokenProvider configured by c.
+func NewTokenProvider(c *sk.SASLConfig) (TokenProvider, error) {
+	switch {
+	case c.Token != "":
+		return StaticToken(c.Token), nil
+	case c.TokenFile != "":
+		return &FileTokenProvider{
+			Path:     c.TokenFile,
+			Lifetime: c.TokenLifetime,
+		}, nil
+	case c.TokenURL != "":
+		return &ClientCredentials{
+			TokenURL:     c.TokenURL,
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			Scopes:       c.Scopes,
+		}, nil
+	default:
+		return nil, errors.New("oauthbearer requires token, token_file or token_url")
+	}
+}
+
+// oauthBearer implements the kafka-go OAUTHBEARER mechanism (RFC 7628).
+type oauthBearer struct {
+	provider TokenProvider
+}
+
+func (oauthBearer) Name() string {
+	return "OAUTHBEARER"
+}
+
+func (m oauthBearer) Start(ctx context.Context) (ksasl.StateMachine, []byte, error) {
+	tok, err := m.provider.Token(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get token: %w", err)
+	}
+	return m, []byte("n,,\x01auth=Bearer " + tok.Value + "\x01\x01"), nil
+}
+
+func (oauthBearer) Next(_ context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) == 0 {
+		return true, nil, nil
+	}
+	// NOTE: server only sends a challenge on failure
+	return false, nil, fmt.Errorf("oauthbearer authentication failed: %s", challenge)
+}
+
+// saramaTokenProvider implements sarama.AccessTokenProvider.
+type saramaTokenProvider struct {
+	provider TokenProvider
+}
+
+func (p saramaTokenProvider) Token() (*sarama.AccessToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+	defer cancel()
+
+	tok, err := p.provider.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sarama.AccessToken{Token: tok.Value}, nil
+}
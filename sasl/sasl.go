@@ -0,0 +1,118 @@
+package sasl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	ksasl "github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	sk "github.com/sko00o/kafka"
+)
+
+type Mechanism string
+
+const (
+	Plain       Mechanism = "plain"
+	ScramSHA256 Mechanism = "scram_sha_256"
+	ScramSHA512 Mechanism = "scram_sha_512"
+	OAuthBearer Mechanism = "oauthbearer"
+)
+
+// ParseMechanism parses the mechanism name of config,
+// an empty name means SASL is disabled.
+func ParseMechanism(name string) (Mechanism, error) {
+	switch v := strings.ToLower(name); v {
+	case "":
+		return "", nil
+	case "plain":
+		return Plain, nil
+	case "scram", "scram_sha_256":
+		return ScramSHA256, nil
+	case "scram_sha_512":
+		return ScramSHA512, nil
+	case "oauthbearer":
+		return OAuthBearer, nil
+	default:
+		return "", fmt.Errorf("sasl mechanism %s not support", name)
+	}
+}
+
+// KafkaGoMechanism returns the kafka-go mechanism of c, or nil if SASL
+// is disabled. provider overrides the token provider from config.
+func KafkaGoMechanism(c *sk.SASLConfig, provider TokenProvider) (ksasl.Mechanism, error) {
+	if c == nil {
+		return nil, nil
+	}
+	m, err := ParseMechanism(c.Mechanism)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m {
+	case Plain:
+		return plain.Mechanism{
+			Username: c.Username,
+			Password: c.Password,
+		}, nil
+	case ScramSHA256:
+		mechanism, err := scram.Mechanism(scram.SHA256, c.Username, c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("new mechanism %s: %w", m, err)
+		}
+		return mechanism, nil
+	case ScramSHA512:
+		mechanism, err := scram.Mechanism(scram.SHA512, c.Username, c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("new mechanism %s: %w", m, err)
+		}
+		return mechanism, nil
+	case OAuthBearer:
+		if provider == nil {
+			if provider, err = NewTokenProvider(c); err != nil {
+				return nil, err
+			}
+		}
+		return oauthBearer{provider: provider}, nil
+	}
+	return nil, nil
+}
+
+// ConfigureSarama enables SASL in cfg if c has a mechanism.
+// provider overrides the token provider from config.
+func ConfigureSarama(cfg *sarama.Config, c *sk.SASLConfig, provider TokenProvider) error {
+	if c == nil {
+		return nil
+	}
+	m, err := ParseMechanism(c.Mechanism)
+	if err != nil {
+		return err
+	}
+
+	switch m {
+	case "":
+		return nil
+	case Plain:
+		cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case ScramSHA256:
+		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClient(sha256Generator)
+	case ScramSHA512:
+		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClient(sha512Generator)
+	case OAuthBearer:
+		if provider == nil {
+			if provider, err = NewTokenProvider(c); err != nil {
+				return err
+			}
+		}
+		cfg.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		cfg.Net.SASL.TokenProvider = saramaTokenProvider{provider: provider}
+	}
+
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.User = c.Username
+	cfg.Net.SASL.Password = c.Password
+	return nil
+}
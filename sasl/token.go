@@ -0,0 +1,185 @@
+package sasl
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+)
+
+// refresh tokens a little before they expire
+const expirySkew = 10 * time.Second
+
+// Token is an OAuth bearer token, a zero Expiry means it never expires.
+type Token = sk.Token
+
+// TokenProvider provides tokens for the oauthbearer mechanism,
+// implementations should cache tokens until they expire.
+type TokenProvider = sk.TokenProvider
+
+func valid(t Token, now time.Time) bool {
+	if t.Value == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || now.Add(expirySkew).Before(t.Expiry)
+}
+
+// StaticToken always provides the same token.
+type StaticToken string
+
+func (t StaticToken) Token(_ context.Context) (Token, error) {
+	if t == "" {
+		return Token{}, errors.New("empty token")
+	}
+	return Token{Value: string(t)}, nil
+}
+
+// FileTokenProvider reads the token from a file, the file is read
+// again once the token expires. The expiry comes from the exp claim
+// if the token is a JWT, otherwise from Lifetime.
+type FileTokenProvider struct {
+	Path     string
+	Lifetime time.Duration
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (p *FileTokenProvider) Token(_ context.Context) (Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if valid(p.token, now) {
+		return p.token, nil
+	}
+
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Token{}, fmt.Errorf("read token file: %w", err)
+	}
+	tok := Token{Value: strings.TrimSpace(string(b))}
+	if tok.Value == "" {
+		return Token{}, fmt.Errorf("empty token file %s", p.Path)
+	}
+	if exp, ok := jwtExpiry(tok.Value); ok {
+		tok.Expiry = exp
+	} else if p.Lifetime > 0 {
+		tok.Expiry = now.Add(p.Lifetime)
+	}
+
+	p.token = tok
+	return tok, nil
+}
+
+// jwtExpiry returns the exp claim of a JWT.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// ClientCredentials fetches tokens from TokenURL with
+// the OAuth 2.0 client credentials grant.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (p *ClientCredentials) Token(ctx context.Context) (Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if valid(p.token, time.Now()) {
+		return p.token, nil
+	}
+
+	tok, err := p.fetch(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	p.token = tok
+	return tok, nil
+}
+
+func (p *ClientCredentials) fetch(ctx context.Context) (Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("new token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, fmt.Errorf("request token: %s: %s", resp.Status, body)
+	}
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return Token{}, fmt.Errorf("decode token response: %w", err)
+	}
+	if res.AccessToken == "" {
+		return Token{}, errors.New("no access_token in token response")
+	}
+
+	tok := Token{Value: res.AccessToken}
+	if res.ExpiresIn > 0 {
+		tok.Expiry = start.Add(time.Duration(res.ExpiresIn) * time.Second)
+	} else if exp, ok := jwtExpiry(tok.Value); ok {
+		tok.Expiry = exp
+	}
+	return tok, nil
+}
@@ -0,0 +1,212 @@
+package sasl
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	sk "github.com/sko00o/kafka"
+)
+
+// newTokenServer stubs a client credentials endpoint, every token
+// it issues is numbered and expires in expiresIn seconds.
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var issued int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("got method %s, want POST", r.Method)
+		}
+		if v := r.Header.Get("Content-Type"); v != "application/x-www-form-urlencoded" {
+			t.Errorf("got content type %s", v)
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+			t.Errorf("got basic auth %q %q %v", id, secret, ok)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if v := r.PostForm.Get("grant_type"); v != "client_credentials" {
+			t.Errorf("got grant_type %s", v)
+		}
+		if v := r.PostForm.Get("scope"); v != "read write" {
+			t.Errorf("got scope %s", v)
+		}
+
+		n := atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func newClientCredentials(url string) *ClientCredentials {
+	return &ClientCredentials{
+		TokenURL:     url,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+}
+
+func TestClientCredentialsCachesToken(t *testing.T) {
+	srv, issued := newTokenServer(t, 3600)
+	p := newClientCredentials(srv.URL)
+
+	for i := 0; i < 3; i++ {
+		tok, err := p.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Value != "token-1" {
+			t.Fatalf("got token %s, want token-1", tok.Value)
+		}
+		if time.Until(tok.Expiry) < time.Hour-time.Minute {
+			t.Fatalf("got expiry %s", tok.Expiry)
+		}
+	}
+	if n := atomic.LoadInt32(issued); n != 1 {
+		t.Fatalf("got %d token requests, want 1", n)
+	}
+}
+
+func TestClientCredentialsRefreshesExpiredToken(t *testing.T) {
+	// NOTE: tokens expiring within expirySkew are refreshed
+	srv, issued := newTokenServer(t, 1)
+	p := newClientCredentials(srv.URL)
+
+	for i := 1; i <= 2; i++ {
+		tok, err := p.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("token-%d", i); tok.Value != want {
+			t.Fatalf("got token %s, want %s", tok.Value, want)
+		}
+	}
+	if n := atomic.LoadInt32(issued); n != 2 {
+		t.Fatalf("got %d token requests, want 2", n)
+	}
+}
+
+func TestClientCredentialsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := newClientCredentials(srv.URL).Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("got error %v, want invalid_client", err)
+	}
+}
+
+func jwt(exp time.Time) string {
+	enc := base64.RawURLEncoding
+	payload := fmt.Sprintf(`{"exp":%d}`, exp.Unix())
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".sig"
+}
+
+func TestFileTokenProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	write := func(v string) {
+		if err := os.WriteFile(path, []byte(v+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// NOTE: an expired JWT is read again on every call
+	expired := jwt(time.Now().Add(-time.Minute))
+	write(expired)
+	p := &FileTokenProvider{Path: path}
+	if tok, err := p.Token(context.Background()); err != nil || tok.Value != expired {
+		t.Fatalf("got token %q, %v", tok.Value, err)
+	}
+	valid := jwt(time.Now().Add(time.Hour))
+	write(valid)
+	if tok, err := p.Token(context.Background()); err != nil || tok.Value != valid {
+		t.Fatalf("got token %q, %v", tok.Value, err)
+	}
+	// NOTE: a valid token is cached
+	write("changed")
+	if tok, err := p.Token(context.Background()); err != nil || tok.Value != valid {
+		t.Fatalf("got token %q, %v", tok.Value, err)
+	}
+}
+
+func TestOAuthBearerMechanisms(t *testing.T) {
+	srv, _ := newTokenServer(t, 3600)
+	c := &sk.SASLConfig{
+		Mechanism:    "oauthbearer",
+		TokenURL:     srv.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+
+	m, err := KafkaGoMechanism(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "n,,\x01auth=Bearer token-1\x01\x01"; string(resp) != want {
+		t.Fatalf("got initial response %q, want %q", resp, want)
+	}
+
+	cfg := sarama.NewConfig()
+	if err := ConfigureSarama(cfg, c, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Net.SASL.Mechanism != sarama.SASLTypeOAuth || !cfg.Net.SASL.Enable {
+		t.Fatalf("got mechanism %s, enable %v", cfg.Net.SASL.Mechanism, cfg.Net.SASL.Enable)
+	}
+	tok, err := cfg.Net.SASL.TokenProvider.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Token != "token-2" {
+		t.Fatalf("got token %s, want token-2", tok.Token)
+	}
+}
+
+func TestOAuthBearerProviderOverridesConfig(t *testing.T) {
+	c := &sk.SASLConfig{Mechanism: "oauthbearer", Token: "from-config"}
+	provider := StaticToken("injected")
+
+	m, err := KafkaGoMechanism(c, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp), "Bearer injected") {
+		t.Fatalf("got initial response %q", resp)
+	}
+
+	cfg := sarama.NewConfig()
+	if err := ConfigureSarama(cfg, c, provider); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := cfg.Net.SASL.TokenProvider.Token()
+	if err != nil || tok.Token != "injected" {
+		t.Fatalf("got token %v, %v", tok, err)
+	}
+}
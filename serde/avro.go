@@ -0,0 +1,132 @@
+package serde
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroSerializer encodes values by schema. Structs are converted through
+// the Avro JSON encoding, so union fields must be in its form, other values
+// must be goavro native ones, e.g. map[string]interface{} for records.
+type AvroSerializer struct {
+	cache  schemaCache
+	codec  *goavro.Codec
+	schema Schema
+	isKey  bool
+}
+
+// NewAvroSerializer registers schema as is, so default, logicalType, doc
+// and aliases are kept, its canonical form only keys the lookups.
+func NewAvroSerializer(r Registry, schema string, opts ...Option) (*AvroSerializer, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("avro schema: %w", err)
+	}
+	return &AvroSerializer{
+		cache:  schemaCache{registry: r},
+		codec:  codec,
+		schema: Schema{Type: Avro, Schema: schema},
+		isKey:  newOptions(opts).isKey,
+	}, nil
+}
+
+func (s *AvroSerializer) Serialize(topic string, v interface{}) ([]byte, error) {
+	id, err := s.cache.id(Subject(topic, s.isKey), s.schema, s.codec.CanonicalSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	native := v
+	if isStruct(v) {
+		text, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("json marshal: %w", err)
+		}
+		if native, _, err = s.codec.NativeFromTextual(text); err != nil {
+			return nil, fmt.Errorf("avro from json: %w", err)
+		}
+	}
+	b, err := s.codec.BinaryFromNative(appendHeader(nil, id), native)
+	if err != nil {
+		return nil, fmt.Errorf("avro encode: %w", err)
+	}
+	return b, nil
+}
+
+func isStruct(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+// AvroDeserializer decodes by the writer schema. v of *interface{} gets
+// the goavro native value, others are filled through the Avro JSON encoding.
+type AvroDeserializer struct {
+	cache schemaCache
+
+	mu     sync.RWMutex
+	codecs map[int]*goavro.Codec
+}
+
+func NewAvroDeserializer(r Registry) *AvroDeserializer {
+	return &AvroDeserializer{
+		cache:  schemaCache{registry: r},
+		codecs: make(map[int]*goavro.Codec),
+	}
+}
+
+func (d *AvroDeserializer) Deserialize(_ string, data []byte, v interface{}) error {
+	id, payload, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	codec, err := d.codec(id)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return fmt.Errorf("avro decode: %w", err)
+	}
+	if p, ok := v.(*interface{}); ok {
+		*p = native
+		return nil
+	}
+	text, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return fmt.Errorf("avro to json: %w", err)
+	}
+	if err := json.Unmarshal(text, v); err != nil {
+		return fmt.Errorf("json unmarshal: %w", err)
+	}
+	return nil
+}
+
+func (d *AvroDeserializer) codec(id int) (*goavro.Codec, error) {
+	d.mu.RLock()
+	codec, ok := d.codecs[id]
+	d.mu.RUnlock()
+	if ok {
+		return codec, nil
+	}
+
+	schema, err := d.cache.schema(id, Avro)
+	if err != nil {
+		return nil, err
+	}
+	codec, err = goavro.NewCodec(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("avro schema %d: %w", id, err)
+	}
+	d.mu.Lock()
+	d.codecs[id] = codec
+	d.mu.Unlock()
+	return codec, nil
+}
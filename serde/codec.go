@@ -0,0 +1,44 @@
+package serde
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Codec adapts a Serializer and Deserializer to sk.Codec of T, to be used
+// by sk.TypedProducer, sk.TypedConsumer and sk.ServeTyped. It is bound to
+// topic, as subjects are named after it. Either of them can be nil if
+// only the other way is used. Nil data decodes to the zero value.
+type Codec[T any] struct {
+	topic string
+	s     Serializer
+	d     Deserializer
+}
+
+func NewCodec[T any](topic string, s Serializer, d Deserializer) *Codec[T] {
+	return &Codec[T]{topic: topic, s: s, d: d}
+}
+
+func (c *Codec[T]) Encode(v T) ([]byte, error) {
+	if c.s == nil {
+		return nil, errors.New("serializer not set")
+	}
+	return c.s.Serialize(c.topic, v)
+}
+
+func (c *Codec[T]) Decode(data []byte) (T, error) {
+	var v T
+	if c.d == nil {
+		return v, errors.New("deserializer not set")
+	}
+	if data == nil {
+		return v, nil
+	}
+	// NOTE: a pointer T, e.g. a proto.Message, is decoded into a new value
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr {
+		v = reflect.New(t.Elem()).Interface().(T)
+		return v, c.d.Deserialize(c.topic, data, v)
+	}
+	err := c.d.Deserialize(c.topic, data, &v)
+	return v, err
+}
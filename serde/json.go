@@ -0,0 +1,77 @@
+package serde
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type options struct {
+	isKey bool
+}
+
+type Option func(*options)
+
+// AsKey makes a Serializer register schemas under the key subject.
+func AsKey() Option {
+	return func(o *options) {
+		o.isKey = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// JSONSerializer encodes values by encoding/json, schema is a JSON Schema
+// registered as is, values are not validated against it.
+type JSONSerializer struct {
+	cache  schemaCache
+	schema Schema
+	isKey  bool
+}
+
+func NewJSONSerializer(r Registry, schema string, opts ...Option) *JSONSerializer {
+	return &JSONSerializer{
+		cache:  schemaCache{registry: r},
+		schema: Schema{Type: JSON, Schema: schema},
+		isKey:  newOptions(opts).isKey,
+	}
+}
+
+func (s *JSONSerializer) Serialize(topic string, v interface{}) ([]byte, error) {
+	id, err := s.cache.id(Subject(topic, s.isKey), s.schema, s.schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json marshal: %w", err)
+	}
+	return append(appendHeader(make([]byte, 0, 5+len(payload)), id), payload...), nil
+}
+
+type JSONDeserializer struct {
+	cache schemaCache
+}
+
+func NewJSONDeserializer(r Registry) *JSONDeserializer {
+	return &JSONDeserializer{cache: schemaCache{registry: r}}
+}
+
+func (d *JSONDeserializer) Deserialize(_ string, data []byte, v interface{}) error {
+	id, payload, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if _, err := d.cache.schema(id, JSON); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("json unmarshal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package serde
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtobufSerializer encodes proto.Message values, the schema is the file
+// descriptor of the message, registered as a base64 serialized
+// FileDescriptorProto without references.
+type ProtobufSerializer struct {
+	cache schemaCache
+	isKey bool
+
+	// schemas of files by path
+	schemas sync.Map
+}
+
+func NewProtobufSerializer(r Registry, opts ...Option) *ProtobufSerializer {
+	return &ProtobufSerializer{
+		cache: schemaCache{registry: r},
+		isKey: newOptions(opts).isKey,
+	}
+}
+
+func (s *ProtobufSerializer) Serialize(topic string, v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not proto.Message", v)
+	}
+	desc := m.ProtoReflect().Descriptor()
+
+	schema, err := s.schema(desc.ParentFile())
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.cache.id(Subject(topic, s.isKey), schema, schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	b := appendIndexes(appendHeader(nil, id), messageIndexes(desc))
+	b, err = proto.MarshalOptions{}.MarshalAppend(b, m)
+	if err != nil {
+		return nil, fmt.Errorf("proto marshal: %w", err)
+	}
+	return b, nil
+}
+
+func (s *ProtobufSerializer) schema(file protoreflect.FileDescriptor) (Schema, error) {
+	if v, ok := s.schemas.Load(file.Path()); ok {
+		return v.(Schema), nil
+	}
+	fd, err := proto.Marshal(protodesc.ToFileDescriptorProto(file))
+	if err != nil {
+		return Schema{}, fmt.Errorf("marshal file descriptor: %w", err)
+	}
+	schema := Schema{Type: Protobuf, Schema: base64.StdEncoding.EncodeToString(fd)}
+	s.schemas.Store(file.Path(), schema)
+	return schema, nil
+}
+
+// messageIndexes returns the path of desc in its file,
+// e.g. [1, 0] is the first nested message of the second message.
+func messageIndexes(desc protoreflect.MessageDescriptor) []int {
+	var indexes []int
+	var d protoreflect.Descriptor = desc
+	for {
+		indexes = append([]int{d.Index()}, indexes...)
+		parent, ok := d.Parent().(protoreflect.MessageDescriptor)
+		if !ok {
+			return indexes
+		}
+		d = parent
+	}
+}
+
+// appendIndexes appends indexes as zigzag varints with the count first,
+// [0] is written as a single 0, the same as Confluent serializers.
+func appendIndexes(b []byte, indexes []int) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(b, 0)
+	}
+	b = append(b, buf[:binary.PutVarint(buf[:], int64(len(indexes)))]...)
+	for _, i := range indexes {
+		b = append(b, buf[:binary.PutVarint(buf[:], int64(i))]...)
+	}
+	return b
+}
+
+// skipIndexes returns payload after the message indexes.
+func skipIndexes(payload []byte) ([]byte, error) {
+	n, size := binary.Varint(payload)
+	if size <= 0 || n < 0 {
+		return nil, fmt.Errorf("%w: message indexes", ErrInvalidWireFormat)
+	}
+	payload = payload[size:]
+	for i := int64(0); i < n; i++ {
+		if _, size = binary.Varint(payload); size <= 0 {
+			return nil, fmt.Errorf("%w: message indexes", ErrInvalidWireFormat)
+		}
+		payload = payload[size:]
+	}
+	return payload, nil
+}
+
+// ProtobufDeserializer decodes into proto.Message values,
+// the message type is the one of v.
+type ProtobufDeserializer struct {
+	cache schemaCache
+}
+
+func NewProtobufDeserializer(r Registry) *ProtobufDeserializer {
+	return &ProtobufDeserializer{cache: schemaCache{registry: r}}
+}
+
+func (d *ProtobufDeserializer) Deserialize(_ string, data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not proto.Message", v)
+	}
+	id, payload, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if _, err := d.cache.schema(id, Protobuf); err != nil {
+		return err
+	}
+	payload, err = skipIndexes(payload)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(payload, m); err != nil {
+		return fmt.Errorf("proto unmarshal: %w", err)
+	}
+	return nil
+}
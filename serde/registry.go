@@ -0,0 +1,174 @@
+package serde
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// SchemaType is the type of a schema, same as the one of Confluent Schema Registry.
+type SchemaType string
+
+const (
+	JSON     SchemaType = "JSON"
+	Protobuf SchemaType = "PROTOBUF"
+	Avro     SchemaType = "AVRO"
+)
+
+type Schema struct {
+	Type   SchemaType
+	Schema string
+}
+
+// Registry is a client of a schema registry.
+type Registry interface {
+	// Register returns the ID of schema under subject,
+	// it is registered if it does not exist yet.
+	Register(subject string, schema Schema) (int, error)
+	// SchemaByID returns the schema of id.
+	SchemaByID(id int) (Schema, error)
+}
+
+// ErrSchemaNotFound is returned by SchemaByID of MemoryRegistry.
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// Subject returns the subject of topic, by the topic name strategy
+// of Confluent Schema Registry.
+func Subject(topic string, isKey bool) string {
+	if isKey {
+		return topic + "-key"
+	}
+	return topic + "-value"
+}
+
+// MemoryRegistry is an in-process Registry for tests, the same schema
+// has the same ID under all subjects. Avro schemas are the same if their
+// canonical forms are, the first one registered is kept.
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	schemas []Schema
+	// IDs by the lookup form of schemas
+	ids      map[Schema]int
+	subjects map[string][]int
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		ids:      make(map[Schema]int),
+		subjects: make(map[string][]int),
+	}
+}
+
+func (r *MemoryRegistry) Register(subject string, schema Schema) (int, error) {
+	if schema.Type == "" {
+		return 0, errors.New("schema type is empty")
+	}
+
+	key := schema
+	if schema.Type == Avro {
+		codec, err := goavro.NewCodec(schema.Schema)
+		if err != nil {
+			return 0, fmt.Errorf("avro schema: %w", err)
+		}
+		key.Schema = codec.CanonicalSchema()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.ids[key]
+	if !ok {
+		r.schemas = append(r.schemas, schema)
+		// NOTE: IDs start from 1, same as Confluent Schema Registry
+		id = len(r.schemas)
+		r.ids[key] = id
+	}
+	for _, v := range r.subjects[subject] {
+		if v == id {
+			return id, nil
+		}
+	}
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id, nil
+}
+
+func (r *MemoryRegistry) SchemaByID(id int) (Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id < 1 || id > len(r.schemas) {
+		return Schema{}, fmt.Errorf("id %d: %w", id, ErrSchemaNotFound)
+	}
+	return r.schemas[id-1], nil
+}
+
+// Versions returns IDs of schemas registered under subject, in order.
+func (r *MemoryRegistry) Versions(subject string) []int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]int(nil), r.subjects[subject]...)
+}
+
+// schemaCache caches IDs of schemas under subjects and schemas of IDs.
+type schemaCache struct {
+	registry Registry
+
+	mu      sync.RWMutex
+	ids     map[subjectSchema]int
+	schemas map[int]Schema
+}
+
+type subjectSchema struct {
+	subject string
+	schema  Schema
+}
+
+// id returns the ID of schema under subject, the cache is keyed by the
+// lookup form of schema, e.g. the canonical form of an Avro schema.
+func (c *schemaCache) id(subject string, schema Schema, lookup string) (int, error) {
+	key := subjectSchema{subject: subject, schema: Schema{Type: schema.Type, Schema: lookup}}
+	c.mu.RLock()
+	id, ok := c.ids[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := c.registry.Register(subject, schema)
+	if err != nil {
+		return 0, fmt.Errorf("register schema of %s: %w", subject, err)
+	}
+	c.mu.Lock()
+	if c.ids == nil {
+		c.ids = make(map[subjectSchema]int)
+	}
+	c.ids[key] = id
+	c.mu.Unlock()
+	return id, nil
+}
+
+func (c *schemaCache) schema(id int, typ SchemaType) (Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if !ok {
+		var err error
+		schema, err = c.registry.SchemaByID(id)
+		if err != nil {
+			return Schema{}, fmt.Errorf("get schema: %w", err)
+		}
+		c.mu.Lock()
+		if c.schemas == nil {
+			c.schemas = make(map[int]Schema)
+		}
+		c.schemas[id] = schema
+		c.mu.Unlock()
+	}
+	if schema.Type != typ {
+		return Schema{}, fmt.Errorf("schema %d is %s, not %s", id, schema.Type, typ)
+	}
+	return schema, nil
+}
@@ -0,0 +1,42 @@
+// Package serde encodes values in the wire format of Confluent Schema
+// Registry: a magic byte 0, a 4-byte big-endian schema ID, then the payload.
+package serde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const magicByte = 0
+
+var ErrInvalidWireFormat = errors.New("invalid wire format")
+
+// Serializer encodes v as the key or value of a message of topic.
+type Serializer interface {
+	Serialize(topic string, v interface{}) ([]byte, error)
+}
+
+// Deserializer decodes the key or value of a message of topic into v,
+// v must be a pointer.
+type Deserializer interface {
+	Deserialize(topic string, data []byte, v interface{}) error
+}
+
+// appendHeader appends the magic byte and id to b.
+func appendHeader(b []byte, id int) []byte {
+	b = append(b, magicByte, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], uint32(id))
+	return b
+}
+
+// parseHeader returns the schema ID and payload of data.
+func parseHeader(data []byte) (int, []byte, error) {
+	if len(data) < 5 {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrInvalidWireFormat, len(data))
+	}
+	if data[0] != magicByte {
+		return 0, nil, fmt.Errorf("%w: magic byte %d", ErrInvalidWireFormat, data[0])
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
@@ -0,0 +1,204 @@
+package serde
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const userSchema = `{
+	"type": "record",
+	"name": "User",
+	"doc": "a user",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int", "default": 0},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}, "default": 0}
+	]
+}`
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestMemoryRegistry(t *testing.T) {
+	r := NewMemoryRegistry()
+	a := Schema{Type: JSON, Schema: `{"type":"object"}`}
+	b := Schema{Type: JSON, Schema: `{"type":"string"}`}
+
+	for _, tc := range []struct {
+		subject string
+		schema  Schema
+		id      int
+	}{
+		{"t-value", a, 1},
+		{"t-value", b, 2},
+		{"t-value", a, 1},
+		{"u-value", a, 1},
+	} {
+		id, err := r.Register(tc.subject, tc.schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != tc.id {
+			t.Fatalf("got id %d of %s under %s, want %d", id, tc.schema.Schema, tc.subject, tc.id)
+		}
+	}
+	if v := r.Versions("t-value"); len(v) != 2 || v[0] != 1 || v[1] != 2 {
+		t.Fatalf("got versions %v, want [1 2]", v)
+	}
+	if s, err := r.SchemaByID(2); err != nil || s != b {
+		t.Fatalf("got schema %v, %v", s, err)
+	}
+	if _, err := r.SchemaByID(3); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("got error %v, want ErrSchemaNotFound", err)
+	}
+	if _, err := r.Register("t-value", Schema{Schema: "{}"}); err == nil {
+		t.Fatal("registered a schema without type")
+	}
+}
+
+func TestAvroRegistersSchemaAsIs(t *testing.T) {
+	r := NewMemoryRegistry()
+	s, err := NewAvroSerializer(r, userSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Serialize("t", user{Name: "a", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := parseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := r.SchemaByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Schema != userSchema {
+		t.Fatalf("got registered schema %s", schema.Schema)
+	}
+
+	// NOTE: a schema of the same canonical form has the same ID
+	compact, err := NewAvroSerializer(r, `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"},{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = compact.Serialize("t", map[string]interface{}{"name": "b", "age": 2, "created": time.UnixMilli(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := parseHeader(data); v != id {
+		t.Fatalf("got id %d of the compact schema, want %d", v, id)
+	}
+}
+
+func TestAvroRoundTrip(t *testing.T) {
+	r := NewMemoryRegistry()
+	s, err := NewAvroSerializer(r, `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`, AsKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Serialize("t", &user{Name: "a", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.Versions("t-key"); len(v) != 1 {
+		t.Fatalf("got versions %v of the key subject", v)
+	}
+
+	var got user
+	if err := NewAvroDeserializer(r).Deserialize("t", data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (user{Name: "a", Age: 1}) {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	r := NewMemoryRegistry()
+	data, err := NewJSONSerializer(r, `{"type":"object"}`).Serialize("t", user{Name: "a", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got user
+	if err := NewJSONDeserializer(r).Deserialize("t", data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (user{Name: "a", Age: 1}) {
+		t.Fatalf("got %+v", got)
+	}
+
+	// NOTE: the writer schema must be of the deserializer type
+	if err := NewAvroDeserializer(r).Deserialize("t", data, &got); err == nil {
+		t.Fatal("avro deserialized a json message")
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	r := NewMemoryRegistry()
+	data, err := NewProtobufSerializer(r).Serialize("t", wrapperspb.String("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(wrapperspb.StringValue)
+	if err := NewProtobufDeserializer(r).Deserialize("t", data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, wrapperspb.String("a")) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestInvalidWireFormat(t *testing.T) {
+	d := NewJSONDeserializer(NewMemoryRegistry())
+	var v interface{}
+	for _, data := range [][]byte{nil, {0, 0, 0}, {1, 0, 0, 0, 1, '{', '}'}} {
+		if err := d.Deserialize("t", data, &v); !errors.Is(err, ErrInvalidWireFormat) {
+			t.Errorf("got error %v of %v, want ErrInvalidWireFormat", err, data)
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	r := NewMemoryRegistry()
+	s, err := NewAvroSerializer(r, `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users sk.Codec[user] = NewCodec[user]("t", s, NewAvroDeserializer(r))
+	data, err := users.Encode(user{Name: "a", Age: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.Versions("t-value"); len(v) != 1 {
+		t.Fatalf("got versions %v of the value subject", v)
+	}
+	if got, err := users.Decode(data); err != nil || got != (user{Name: "a", Age: 1}) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if got, err := users.Decode(nil); err != nil || got != (user{}) {
+		t.Fatalf("got %+v, %v of nil", got, err)
+	}
+
+	// NOTE: a proto.Message is decoded into a new message
+	var strs sk.Codec[*wrapperspb.StringValue] = NewCodec[*wrapperspb.StringValue]("t", NewProtobufSerializer(r), NewProtobufDeserializer(r))
+	data, err = strs.Encode(wrapperspb.String("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := strs.Decode(data); err != nil || !proto.Equal(got, wrapperspb.String("a")) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	if _, err := NewCodec[user]("t", nil, nil).Encode(user{}); err == nil {
+		t.Fatal("encoded without a serializer")
+	}
+}
@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// HandlerFunc processes a single message.
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// ErrorPolicy decides the fate of a message its handler failed on,
+// returning nil acks the message, an error stops Serve with it.
+type ErrorPolicy func(ctx context.Context, msg Message, err error) error
+
+// StopOnError stops Serve with the handler error, the message is not acked.
+func StopOnError(_ context.Context, _ Message, err error) error {
+	return err
+}
+
+// SkipOnError acks the failed message and keeps serving.
+func SkipOnError(_ context.Context, _ Message, _ error) error {
+	return nil
+}
+
+type ServeOption func(*server)
+
+// WithErrorPolicy overrides error_policy of config.
+func WithErrorPolicy(p ErrorPolicy) ServeOption {
+	return func(s *server) {
+		s.policy = p
+	}
+}
+
+type server struct {
+	handler    HandlerFunc
+	policy     ErrorPolicy
+	byKey      bool
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Serve runs c and processes its messages by worker_cnt workers until ctx
+// is done or the error policy gives up. Messages of one partition, or of
+// one key with ordering "key", are handled in order by the same worker.
+// A failed handler is retried max_retries times before the error policy
+// applies. Offsets are only committed for processed messages, so
+// manual_ack is required. c is stopped when Serve returns.
+func Serve(ctx context.Context, c Consumer, cfg ConsumerConfig, handler HandlerFunc, options ...ServeOption) error {
+	if !cfg.ManualAck {
+		return errors.New("serve requires manual_ack")
+	}
+	if cfg.Batch != nil {
+		return errors.New("serve does not support batch mode")
+	}
+
+	s := &server{
+		handler:    handler,
+		maxRetries: cfg.MaxRetries,
+		backoff:    defaultRetryBackoff,
+	}
+	if v := cfg.RetryBackoff; v > 0 {
+		s.backoff = v
+	}
+	switch v := cfg.ErrorPolicy; v {
+	case "", "stop":
+		s.policy = StopOnError
+	case "skip":
+		s.policy = SkipOnError
+	default:
+		return fmt.Errorf("error_policy %s not support", v)
+	}
+	switch v := cfg.Ordering; v {
+	case "", "partition":
+	case "key":
+		s.byKey = true
+	default:
+		return fmt.Errorf("ordering %s not support", v)
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	workers := int(cfg.WorkerCnt)
+	if workers < 1 {
+		workers = 1
+	}
+	return s.serve(ctx, c, workers)
+}
+
+func (s *server) serve(parent context.Context, c Consumer, workers int) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	if err := c.Run(); err != nil {
+		// NOTE: Run may have started part of the consumer
+		c.Stop()
+		return err
+	}
+
+	var (
+		once     sync.Once
+		serveErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			serveErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan Message, workers)
+	for i := range queues {
+		queues[i] = make(chan Message, 1)
+		wg.Add(1)
+		go func(queue <-chan Message) {
+			defer wg.Done()
+			for msg := range queue {
+				if ctx.Err() != nil {
+					// NOTE: leave it uncommitted, it will be consumed again
+					continue
+				}
+				if err := s.process(ctx, msg); err != nil {
+					msg.Nack()
+					if ctx.Err() == nil {
+						fail(err)
+					}
+					continue
+				}
+				msg.Ack()
+			}
+		}(queues[i])
+	}
+
+	msgs := c.Receive()
+dispatch:
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				break dispatch
+			}
+			select {
+			case queues[s.worker(msg, workers)] <- msg:
+			case <-ctx.Done():
+				break dispatch
+			}
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	// NOTE: in a goroutine, msgs is only closed by Stop
+	go drain(msgs)
+	c.Stop()
+
+	return serveErr
+}
+
+func (s *server) process(ctx context.Context, msg Message) error {
+	err := s.handler(ctx, msg)
+	backoff := s.backoff
+	// NOTE: retries never fix a message failed to decode
+	for i := 0; err != nil && i < s.maxRetries && !isDecodeError(err); i++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+		err = s.handler(ctx, msg)
+	}
+	if err != nil {
+		return s.policy(ctx, msg, err)
+	}
+	return nil
+}
+
+func (s *server) worker(msg Message, workers int) int {
+	h := fnv.New32a()
+	if key := msg.Key(); s.byKey && key != nil {
+		_, _ = h.Write(key)
+	} else {
+		_, _ = h.Write([]byte(msg.Topic()))
+		p := msg.Partition()
+		_, _ = h.Write([]byte{byte(p >> 24), byte(p >> 16), byte(p >> 8), byte(p)})
+	}
+	return int(h.Sum32() % uint32(workers))
+}
+
+// drain receives msgs until they are closed, so a consumer stopping is
+// not blocked on sending a message nobody receives.
+func drain(msgs <-chan Message) {
+	for range msgs {
+	}
+}
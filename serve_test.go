@@ -0,0 +1,160 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/internal/testkit"
+	"github.com/sko00o/kafka/memory"
+)
+
+// failedConsumer fails to run, as a consumer that can not join its group.
+type failedConsumer struct {
+	sk.Consumer
+	stopped bool
+}
+
+func (c *failedConsumer) Run() error {
+	return errors.New("run failed")
+}
+
+func (c *failedConsumer) Stop() {
+	c.stopped = true
+}
+
+func TestServeStopsFailedConsumer(t *testing.T) {
+	c := &failedConsumer{}
+	err := sk.Serve(context.Background(), c, sk.ConsumerConfig{ManualAck: true}, func(context.Context, sk.Message) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("serve of a failed consumer succeeded")
+	}
+	if !c.stopped {
+		t.Fatal("failed consumer is not stopped")
+	}
+}
+
+func newProducer(t *testing.T, b *memory.Broker) *memory.Producer {
+	t.Helper()
+	p, err := memory.NewProducer(b, sk.ProducerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+// produce sends values to partition p of topic.
+func produce(t *testing.T, pr sk.Producer, topic string, p int32, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		if err := pr.SendMessage(context.Background(), &sk.ProducerMessage{Topic: topic, Partition: &p, Value: []byte(v)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	b := memory.NewBroker(4)
+	if err := b.CreateTopic("test", 4); err != nil {
+		t.Fatal(err)
+	}
+	p := newProducer(t, b)
+	const n = 20
+	for i := 0; i < n; i++ {
+		produce(t, p, "test", int32(i%4), fmt.Sprint(i))
+	}
+
+	c, err := memory.NewConsumer(b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu       sync.Mutex
+		offsets  = make(map[int32][]int64)
+		attempts = make(map[string]int)
+		handled  int
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := sk.ConsumerConfig{ManualAck: true, WorkerCnt: 4, MaxRetries: 1, RetryBackoff: time.Millisecond, ErrorPolicy: "skip"}
+	done := make(chan error, 1)
+	go func() {
+		done <- sk.Serve(ctx, c, cfg, func(_ context.Context, msg sk.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			v := string(msg.Value())
+			attempts[v]++
+			switch {
+			case v == "5" && attempts[v] == 1:
+				// NOTE: fixed by the retry
+				return fmt.Errorf("fail %s once", v)
+			case v == "6":
+				// NOTE: skipped by the error policy
+				return fmt.Errorf("fail %s", v)
+			}
+			offsets[msg.Partition()] = append(offsets[msg.Partition()], msg.Offset())
+			handled++
+			return nil
+		})
+	}()
+
+	committed := func() bool {
+		for i := int32(0); i < 4; i++ {
+			if offset, ok := b.Committed("group", "test", i); !ok || offset != b.HighWaterMark("test", i) {
+				return false
+			}
+		}
+		return true
+	}
+	testkit.WaitFor(t, "all committed", committed)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if handled != n-1 {
+		t.Fatalf("handled %d messages, want %d", handled, n-1)
+	}
+	if attempts["5"] != 2 || attempts["6"] != 2 {
+		t.Fatalf("got attempts %d of 5 and %d of 6, want 2", attempts["5"], attempts["6"])
+	}
+	for partition, os := range offsets {
+		for i := 1; i < len(os); i++ {
+			if os[i] <= os[i-1] {
+				t.Fatalf("partition %d handled out of order: %v", partition, os)
+			}
+		}
+	}
+}
+
+func TestServeStopsOnError(t *testing.T) {
+	b := memory.NewBroker(1)
+	produce(t, newProducer(t, b), "test", 0, "ok", "bad", "next")
+
+	c, err := memory.NewConsumer(b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sk.Serve(context.Background(), c, sk.ConsumerConfig{ManualAck: true}, func(_ context.Context, msg sk.Message) error {
+		if string(msg.Value()) == "bad" {
+			return fmt.Errorf("bad message")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("serve did not stop on the handler error")
+	}
+	// NOTE: the failed message is consumed again by the next member
+	if offset, _ := b.Committed("group", "test", 0); offset != 1 {
+		t.Fatalf("committed %d, want 1", offset)
+	}
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Build returns the tls.Config described by c, or nil if TLS is disabled.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	if c == nil || !c.Enable {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if v := c.MinVersion; v != "" {
+		switch v {
+		case "1.0":
+			cfg.MinVersion = tls.VersionTLS10
+		case "1.1":
+			cfg.MinVersion = tls.VersionTLS11
+		case "1.2":
+			cfg.MinVersion = tls.VersionTLS12
+		case "1.3":
+			cfg.MinVersion = tls.VersionTLS13
+		default:
+			return nil, fmt.Errorf("tls min_version %s not support", v)
+		}
+	}
+
+	if v := c.CAFile; v != "" {
+		pem, err := os.ReadFile(v)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", v)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
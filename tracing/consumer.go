@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	sk "github.com/sko00o/kafka"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extract returns ctx with the span context in headers of msg, if any.
+func Extract(ctx context.Context, msg sk.Message, options ...Option) context.Context {
+	return newConfig(options).extract(ctx, msg)
+}
+
+func (c config) extract(ctx context.Context, msg sk.Message) context.Context {
+	headers := msg.Headers()
+	return c.propagator.Extract(ctx, HeaderCarrier{Headers: &headers})
+}
+
+// Start starts a span of processing msg, as a child of the span
+// context in its headers, the caller must end it.
+func Start(ctx context.Context, msg sk.Message, options ...Option) (context.Context, trace.Span) {
+	return newConfig(options).start(ctx, msg)
+}
+
+func (c config) start(ctx context.Context, msg sk.Message) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystem("kafka"),
+		semconv.MessagingSourceName(msg.Topic()),
+		semconv.MessagingOperationProcess,
+		semconv.MessagingKafkaSourcePartitionKey.Int(int(msg.Partition())),
+		semconv.MessagingKafkaMessageOffsetKey.Int64(msg.Offset()),
+	}
+	if key := msg.Key(); key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(string(key)))
+	}
+	if c.groupID != "" {
+		attrs = append(attrs, semconv.MessagingKafkaConsumerGroup(c.groupID))
+	}
+	return c.tracer().Start(c.extract(ctx, msg), fmt.Sprintf("%s process", msg.Topic()),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// Handler wraps h for sk.Serve, every message is handled in a span of Start.
+func Handler(h sk.HandlerFunc, options ...Option) sk.HandlerFunc {
+	c := newConfig(options)
+	return func(ctx context.Context, msg sk.Message) error {
+		ctx, span := c.start(ctx, msg)
+		defer span.End()
+
+		if err := h(ctx, msg); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		return nil
+	}
+}
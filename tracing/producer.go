@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	sk "github.com/sko00o/kafka"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Producer creates a span for every message sent and injects its
+// context into headers. Spans of async producers end when the message
+// is queued, not delivered.
+type Producer struct {
+	sk.Producer
+	config config
+}
+
+// NewProducer wraps p, every Send goes through SendMessage of p.
+func NewProducer(p sk.Producer, options ...Option) *Producer {
+	return &Producer{Producer: p, config: newConfig(options)}
+}
+
+func (p *Producer) Send(topic string, value []byte) error {
+	return p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: topic, Value: value})
+}
+
+func (p *Producer) SendContext(ctx context.Context, topic string, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{Topic: topic, Value: value})
+}
+
+func (p *Producer) SendWithKey(topic string, key, value []byte) error {
+	return p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: topic, Key: key, Value: value})
+}
+
+func (p *Producer) SendWithKeyContext(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessage(ctx, &sk.ProducerMessage{Topic: topic, Key: key, Value: value})
+}
+
+// SendMessage sends msg in a span of the parent in ctx, a shallow copy
+// of msg with the trace context in its headers is sent, so msg is left
+// as is, delivery reports carry the copy.
+func (p *Producer) SendMessage(ctx context.Context, msg *sk.ProducerMessage) error {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystem("kafka"),
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingOperationPublish,
+	}
+	if msg.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(string(msg.Key)))
+	}
+	if msg.Partition != nil {
+		attrs = append(attrs, semconv.MessagingKafkaDestinationPartitionKey.Int(int(*msg.Partition)))
+	}
+	ctx, span := p.config.tracer().Start(ctx, fmt.Sprintf("%s publish", msg.Topic),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	// NOTE: copy headers, so the backing array of the caller is not modified
+	headers := append(make([]sk.Header, 0, len(msg.Headers)+1), msg.Headers...)
+	p.config.propagator.Inject(ctx, HeaderCarrier{Headers: &headers})
+	sent := *msg
+	sent.Headers = headers
+
+	if err := p.Producer.SendMessage(ctx, &sent); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// TxnProducer is Producer of a transactional producer, transactions are
+// not traced, only messages sent in them.
+type TxnProducer struct {
+	*Producer
+	txn sk.TxnProducer
+}
+
+// NewTxnProducer wraps p, it implements sk.TxnProducer as p does.
+func NewTxnProducer(p sk.TxnProducer, options ...Option) *TxnProducer {
+	return &TxnProducer{Producer: NewProducer(p, options...), txn: p}
+}
+
+func (p *TxnProducer) BeginTxn() error {
+	return p.txn.BeginTxn()
+}
+
+func (p *TxnProducer) CommitTxn() error {
+	return p.txn.CommitTxn()
+}
+
+func (p *TxnProducer) AbortTxn() error {
+	return p.txn.AbortTxn()
+}
+
+func (p *TxnProducer) SendOffsetsToTxn(offsets []sk.PartitionOffset, groupID string) error {
+	return p.txn.SendOffsetsToTxn(offsets, groupID)
+}
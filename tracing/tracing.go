@@ -0,0 +1,91 @@
+// Package tracing traces producing and consuming by OpenTelemetry,
+// trace context is propagated in message headers.
+package tracing
+
+import (
+	sk "github.com/sko00o/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sko00o/kafka/tracing"
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	groupID    string
+}
+
+type Option func(*config)
+
+// WithTracerProvider sets the provider of tracers,
+// the global one is used by default.
+func WithTracerProvider(p trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = p
+	}
+}
+
+// WithPropagator sets the propagator of trace context in headers,
+// W3C trace context is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithGroupID sets the consumer group of consumer spans.
+func WithGroupID(id string) Option {
+	return func(c *config) {
+		c.groupID = id
+	}
+}
+
+func newConfig(options []Option) config {
+	c := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+func (c config) tracer() trace.Tracer {
+	return c.provider.Tracer(instrumentationName)
+}
+
+// HeaderCarrier adapts headers of a message to propagation.TextMapCarrier.
+type HeaderCarrier struct {
+	Headers *[]sk.Header
+}
+
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header of key, if any.
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, sk.Header{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
@@ -0,0 +1,131 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/memory"
+	"github.com/sko00o/kafka/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProduceAndConsume(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	b := memory.NewBroker(1)
+	mp, err := memory.NewProducer(b, sk.ProducerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Stop()
+	p := tracing.NewProducer(mp, tracing.WithTracerProvider(tp))
+
+	headers := []sk.Header{{Key: "h", Value: []byte("v")}}
+	msg := &sk.ProducerMessage{Topic: "test", Key: []byte("k"), Value: []byte("v"), Headers: headers}
+	if err := p.SendMessage(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Headers) != 1 || len(headers) != 1 {
+		t.Fatalf("got headers %v of the message sent", msg.Headers)
+	}
+
+	cfg := sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first", ManualAck: true}
+	c, err := memory.NewConsumer(b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan trace.SpanContext, 1)
+	go func() {
+		_ = sk.Serve(ctx, c, cfg, tracing.Handler(func(ctx context.Context, msg sk.Message) error {
+			handled <- trace.SpanContextFromContext(ctx)
+			return nil
+		}, tracing.WithTracerProvider(tp), tracing.WithGroupID("group")))
+	}()
+
+	var sc trace.SpanContext
+	select {
+	case sc = <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message handled")
+	}
+	cancel()
+
+	// NOTE: the consumer span ends after the handler returns
+	var spans tracetest.SpanStubs
+	deadline := time.Now().Add(5 * time.Second)
+	for len(spans) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d spans, want 2", len(spans))
+		}
+		time.Sleep(time.Millisecond)
+		spans = exporter.GetSpans()
+	}
+	publish, process := spans[0], spans[1]
+	if publish.Name != "test publish" || publish.SpanKind != trace.SpanKindProducer {
+		t.Fatalf("got span %s of kind %s, want the publish span", publish.Name, publish.SpanKind)
+	}
+	if process.SpanKind != trace.SpanKindConsumer || process.SpanContext.SpanID() != sc.SpanID() {
+		t.Fatalf("got span %s of kind %s, want the process span", process.Name, process.SpanKind)
+	}
+	if process.Parent.SpanID() != publish.SpanContext.SpanID() || process.SpanContext.TraceID() != publish.SpanContext.TraceID() {
+		t.Fatal("process span is not a child of the publish span")
+	}
+}
+
+func TestTxnProducer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	b := memory.NewBroker(1)
+	mp, err := memory.NewProducer(b, sk.ProducerConfig{TransactionalID: "txn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Stop()
+	var p sk.TxnProducer = tracing.NewTxnProducer(mp, tracing.WithTracerProvider(tp))
+
+	if err := p.BeginTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "test", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SendOffsetsToTxn([]sk.PartitionOffset{{Topic: "in", Partition: 0, Offset: 1}}, "group"); err != nil {
+		t.Fatal(err)
+	}
+	if n := b.HighWaterMark("test", 0); n != 0 {
+		t.Fatalf("got %d messages before commit", n)
+	}
+	if err := p.CommitTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if n := b.HighWaterMark("test", 0); n != 1 {
+		t.Fatalf("got %d messages after commit, want 1", n)
+	}
+	if offset, ok := b.Committed("group", "in", 0); !ok || offset != 1 {
+		t.Fatalf("got offset %d, %v committed by the transaction, want 1", offset, ok)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 1 || spans[0].Name != "test publish" {
+		t.Fatalf("got spans %v, want the publish span", spans)
+	}
+
+	if err := p.BeginTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Send("test", []byte("aborted")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AbortTxn(); err != nil {
+		t.Fatal(err)
+	}
+	if n := b.HighWaterMark("test", 0); n != 1 {
+		t.Fatalf("got %d messages after abort, want 1", n)
+	}
+}
@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DecodeError is the error of decoding a message by Codec,
+// Serve does not retry it.
+type DecodeError struct {
+	// IsKey is true if the key failed, the value otherwise
+	IsKey bool
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	if e.IsKey {
+		return fmt.Sprintf("decode key: %v", e.Err)
+	}
+	return fmt.Sprintf("decode value: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func isDecodeError(err error) bool {
+	var e *DecodeError
+	return errors.As(err, &e)
+}
+
+// TypedProducer sends keys and values of K and V encoded by Codecs.
+type TypedProducer[K, V any] struct {
+	Producer
+	key   Codec[K]
+	value Codec[V]
+}
+
+func NewTypedProducer[K, V any](p Producer, key Codec[K], value Codec[V]) *TypedProducer[K, V] {
+	return &TypedProducer[K, V]{Producer: p, key: key, value: value}
+}
+
+// Produce sends value with key to topic, headers are optional.
+func (p *TypedProducer[K, V]) Produce(ctx context.Context, topic string, key K, value V, headers ...Header) error {
+	k, err := p.key.Encode(key)
+	if err != nil {
+		return fmt.Errorf("encode key: %w", err)
+	}
+	v, err := p.value.Encode(value)
+	if err != nil {
+		return fmt.Errorf("encode value: %w", err)
+	}
+	return p.SendMessage(ctx, &ProducerMessage{
+		Topic:   topic,
+		Key:     k,
+		Value:   v,
+		Headers: headers,
+	})
+}
+
+// TypedMessage is a message decoded by Codecs, Err is the DecodeError
+// of it, Key and Value are zero values if they failed.
+type TypedMessage[K, V any] struct {
+	Key   K
+	Value V
+	Err   error
+	// Raw is the message received
+	Raw Message
+}
+
+func (m TypedMessage[K, V]) Ack() {
+	m.Raw.Ack()
+}
+
+func (m TypedMessage[K, V]) Nack() {
+	m.Raw.Nack()
+}
+
+func decode[K, V any](msg Message, key Codec[K], value Codec[V]) TypedMessage[K, V] {
+	m := TypedMessage[K, V]{Raw: msg}
+	var err error
+	if m.Key, err = key.Decode(msg.Key()); err != nil {
+		m.Err = &DecodeError{IsKey: true, Err: err}
+		return m
+	}
+	if m.Value, err = value.Decode(msg.Value()); err != nil {
+		m.Err = &DecodeError{Err: err}
+	}
+	return m
+}
+
+// TypedConsumer receives messages decoded by Codecs,
+// batch mode is not supported.
+type TypedConsumer[K, V any] struct {
+	Consumer
+	key     Codec[K]
+	value   Codec[V]
+	msgChan chan TypedMessage[K, V]
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+func NewTypedConsumer[K, V any](c Consumer, key Codec[K], value Codec[V]) *TypedConsumer[K, V] {
+	return &TypedConsumer[K, V]{Consumer: c, key: key, value: value, done: make(chan struct{})}
+}
+
+// Run runs the underlying consumer, Receive returns its messages then.
+func (c *TypedConsumer[K, V]) Run() error {
+	if err := c.Consumer.Run(); err != nil {
+		return err
+	}
+
+	in := c.Consumer.Receive()
+	c.msgChan = make(chan TypedMessage[K, V], cap(in))
+	c.wg.Add(1)
+	go c.forward(in)
+	return nil
+}
+
+func (c *TypedConsumer[K, V]) Receive() <-chan TypedMessage[K, V] {
+	return c.msgChan
+}
+
+// Stop stops the underlying consumer, messages not received are dropped.
+func (c *TypedConsumer[K, V]) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+		c.Consumer.Stop()
+		c.wg.Wait()
+	})
+}
+
+func (c *TypedConsumer[K, V]) forward(in <-chan Message) {
+	defer c.wg.Done()
+	defer close(c.msgChan)
+
+	for msg := range in {
+		select {
+		case c.msgChan <- decode(msg, c.key, c.value):
+		case <-c.done:
+			// NOTE: Stop closes in after done and waits for it
+			drain(in)
+			return
+		}
+	}
+}
+
+// TypedHandlerFunc handles a decoded message of ServeTyped.
+type TypedHandlerFunc[K, V any] func(ctx context.Context, key K, value V, msg Message) error
+
+// ServeTyped is Serve with messages decoded by Codecs, a message failed
+// to decode is not passed to handler, its DecodeError goes to the error policy.
+func ServeTyped[K, V any](ctx context.Context, c Consumer, cfg ConsumerConfig, key Codec[K], value Codec[V], handler TypedHandlerFunc[K, V], options ...ServeOption) error {
+	return Serve(ctx, c, cfg, func(ctx context.Context, msg Message) error {
+		m := decode(msg, key, value)
+		if m.Err != nil {
+			return m.Err
+		}
+		return handler(ctx, m.Key, m.Value, msg)
+	}, options...)
+}
@@ -0,0 +1,74 @@
+package kafka_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sk "github.com/sko00o/kafka"
+	"github.com/sko00o/kafka/memory"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestTypedConsumerStop(t *testing.T) {
+	b := memory.NewBroker(1)
+	p, err := memory.NewProducer(b, sk.ProducerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	for i := 0; i < 10; i++ {
+		if err := p.SendMessage(context.Background(), &sk.ProducerMessage{Topic: "test", Value: []byte(`{"name":"a"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inner, err := memory.NewConsumer(b, sk.ConsumerConfig{Topics: []string{"test"}, GroupID: "group", StartOffset: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := sk.NewTypedConsumer[string, user](inner, sk.StringCodec{}, sk.JSONCodec[user]{})
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-c.Receive():
+		if msg.Err != nil || msg.Value.Name != "a" {
+			t.Fatalf("got %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	// NOTE: messages not received yet are dropped
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop hangs")
+	}
+	n := 0
+	for range c.Receive() {
+		n++
+	}
+	if n > cap(c.Receive()) {
+		t.Fatalf("got %d messages after stop", n)
+	}
+}
+
+func TestJSONCodecNilKey(t *testing.T) {
+	v, err := sk.JSONCodec[user]{}.Decode(nil)
+	if err != nil || v != (user{}) {
+		t.Fatalf("got %+v, %v of a nil key", v, err)
+	}
+	if _, err := (sk.JSONCodec[user]{}).Decode([]byte{}); err == nil {
+		t.Fatal("decoded empty data")
+	}
+}